@@ -0,0 +1,133 @@
+package load
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrUnsupportedCompression is returned for compressed input in a format
+// that cannot be decompressed, such as zstd.
+var ErrUnsupportedCompression = errors.New("unsupported compression")
+
+// compressionExtensions are the file extensions of compressed documents,
+// which are ignored when deriving the format of a document from its name.
+var compressionExtensions = []string{".gz", ".gzip", ".bz2", ".zst"}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Decompress wraps a reader so that gzip and bzip2 streams, recognised by
+// their magic bytes, are decompressed while anything else is passed
+// through unchanged. Zstandard streams fail with ErrUnsupportedCompression.
+func Decompress(r io.Reader) io.Reader {
+	return &decompressReader{src: r}
+}
+
+// decompressReader picks the decompressor on the first read.
+type decompressReader struct {
+	src io.Reader
+	r   io.Reader
+	err error
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = decompressor(d.src)
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+// decompressor returns a reader of the decompressed content of r.
+func decompressor(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	switch compression(magic) {
+	case "gzip":
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		return zr, nil
+	case "bzip2":
+		return bzip2.NewReader(br), nil
+	case "zstd":
+		return nil, fmt.Errorf("%w: zstd", ErrUnsupportedCompression)
+	default:
+		return br, nil
+	}
+}
+
+// compression names the compression format that the leading bytes of a
+// document belong to, or returns an empty string.
+func compression(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return "gzip"
+	case bytes.HasPrefix(magic, bzip2Magic) && len(magic) > len(bzip2Magic) &&
+		magic[len(bzip2Magic)] >= '1' && magic[len(bzip2Magic)] <= '9':
+		return "bzip2"
+	case bytes.HasPrefix(magic, zstdMagic):
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// decompressBytes decompresses a whole document when it is compressed,
+// honouring MaxBytes for the decompressed size.
+func decompressBytes(data []byte, o *options) ([]byte, error) {
+	if compression(data[:min(len(data), len(zstdMagic))]) == "" {
+		return data, nil
+	}
+	return readAll(Decompress(bytes.NewReader(data)), o)
+}
+
+// compressBytes compresses a document in the named compression format,
+// returning it unchanged when the format is empty. Only gzip can be
+// written with the standard library.
+func compressBytes(data []byte, format string) ([]byte, error) {
+	switch format {
+	case "":
+		return data, nil
+	case "gzip":
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%w: cannot write %s", ErrUnsupportedCompression, format)
+	}
+}
+
+// trimCompressionExt removes the extension of a compressed file, turning
+// "config.json.gz" into "config.json".
+func trimCompressionExt(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	for _, compressed := range compressionExtensions {
+		if ext == compressed {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
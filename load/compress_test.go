@@ -0,0 +1,186 @@
+package load
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBzip2YAML is "name: api\nport: 8080\n" compressed with bzip2, which
+// the standard library can only read.
+var testBzip2YAML = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x9d, 0xd1,
+	0xf8, 0x36, 0x00, 0x00, 0x07, 0xd9, 0x80, 0x00, 0x10, 0x40, 0x00, 0x40,
+	0x50, 0x22, 0x23, 0xd4, 0x00, 0x20, 0x00, 0x22, 0x0d, 0x1a, 0x43, 0x08,
+	0x40, 0x00, 0x01, 0xc2, 0x85, 0x1c, 0xef, 0x69, 0xeb, 0x2e, 0xc0, 0x19,
+	0xbf, 0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0x9d, 0xd1, 0xf8, 0x36,
+}
+
+type testCompressConfig struct {
+	Name string `yaml:"name" json:"name"`
+	Port int    `yaml:"port" json:"port"`
+}
+
+func testGzip(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{name: "Gzip", input: testGzip(t, "name: api\nport: 8080\n"), want: "name: api\nport: 8080\n"},
+		{name: "Bzip2", input: testBzip2YAML, want: "name: api\nport: 8080\n"},
+		{name: "Plain", input: []byte(`{"name": "api"}`), want: `{"name": "api"}`},
+		{name: "BZhWithoutLevel", input: []byte("BZh: text"), want: "BZh: text"},
+		{name: "Short", input: []byte("a"), want: "a"},
+		{name: "Empty", input: nil, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(Decompress(bytes.NewReader(tt.input)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestDecompress_Errors(t *testing.T) {
+	_, err := io.ReadAll(Decompress(bytes.NewReader([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00})))
+	assert.ErrorIs(t, err, ErrUnsupportedCompression)
+	assert.EqualError(t, err, "unsupported compression: zstd")
+
+	_, err = io.ReadAll(Decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0x00})))
+	assert.ErrorContains(t, err, "invalid gzip data")
+}
+
+func TestDecompress_Loaders(t *testing.T) {
+	got, err := FromYAML[testCompressConfig](Decompress(bytes.NewReader(testBzip2YAML)))
+	require.NoError(t, err)
+	assert.Equal(t, testCompressConfig{Name: "api", Port: 8080}, got)
+
+	got, err = FromJSON[testCompressConfig](Decompress(strings.NewReader(`{"name": "web", "port": 80}`)))
+	require.NoError(t, err)
+	assert.Equal(t, testCompressConfig{Name: "web", Port: 80}, got)
+}
+
+func TestFromFile_Compressed(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"config.json.gz":  testGzip(t, `{"name": "api", "port": 8080}`),
+		"config.yaml.bz2": testBzip2YAML,
+		"config.gz":       testGzip(t, "name = \"api\"\nport = 8080\n"),
+	}
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+	}
+
+	for name := range files {
+		t.Run(name, func(t *testing.T) {
+			got, err := FromFile[testCompressConfig](filepath.Join(dir, name))
+			require.NoError(t, err)
+			assert.Equal(t, testCompressConfig{Name: "api", Port: 8080}, got)
+		})
+	}
+}
+
+func TestFromFS_CompressedErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml.zst": &fstest.MapFile{Data: []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}},
+		"large.yaml.gz":   &fstest.MapFile{Data: testGzip(t, "name: "+strings.Repeat("a", 1024)+"\n")},
+	}
+
+	_, err := FromFS[testCompressConfig](fsys, "config.yaml.zst")
+	assert.ErrorIs(t, err, ErrUnsupportedCompression)
+	assert.EqualError(t, err, "failed to load config.yaml.zst: unsupported compression: zstd")
+
+	_, err = FromFS[testCompressConfig](fsys, "large.yaml.gz", MaxBytes(512))
+	var sizeErr *SizeLimitError
+	require.ErrorAs(t, err, &sizeErr)
+	assert.Equal(t, int64(512), sizeErr.Limit)
+}
+
+func TestLayered_CompressedFile(t *testing.T) {
+	fsys := fstest.MapFS{"config.yaml.bz2": &fstest.MapFile{Data: testBzip2YAML}}
+
+	got, err := NewLayered[testCompressConfig](FSSource("compressed", fsys, "config.yaml.bz2")).Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, testCompressConfig{Name: "api", Port: 8080}, got.Value)
+}
+
+func TestIncludes_Compressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml":     &fstest.MapFile{Data: []byte("db: !include db.yaml.gz\nmeta: !include meta.yaml.bz2\n")},
+		"db.yaml.gz":      &fstest.MapFile{Data: testGzip(t, "name: db\nport: 5432\n")},
+		"meta.yaml.bz2":   &fstest.MapFile{Data: testBzip2YAML},
+		"broken.yaml":     &fstest.MapFile{Data: []byte("db: !include broken.json.zst\n")},
+		"broken.json.zst": &fstest.MapFile{Data: []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}},
+	}
+
+	got, err := FromFS[map[string]testCompressConfig](fsys, "config.yaml", Includes(fsys))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]testCompressConfig{
+		"db":   {Name: "db", Port: 5432},
+		"meta": {Name: "api", Port: 8080},
+	}, got)
+
+	_, err = FromFS[map[string]testCompressConfig](fsys, "broken.yaml", Includes(fsys))
+	var includeErr *IncludeError
+	require.ErrorAs(t, err, &includeErr)
+	assert.ErrorIs(t, err, ErrUnsupportedCompression)
+}
+
+func TestMigrateFile_Compressed(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.yaml.gz")
+	require.NoError(t, os.WriteFile(name, testGzip(t, "version: 1\nhost: localhost\nport: 8080\n"), 0o600))
+
+	applied, err := MigrateFile(name, testMigrations()...)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", compression(data[:2]))
+	got, err := FromFile[testMigratedConfig](name)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Version)
+	assert.Equal(t, "localhost", got.Server.Host)
+
+	bzName := filepath.Join(dir, "config.yaml.bz2")
+	require.NoError(t, os.WriteFile(bzName, testBzip2YAML, 0o600))
+	_, err = MigrateFile(bzName, Migration{From: 0, To: 1})
+	assert.ErrorIs(t, err, ErrUnsupportedCompression)
+	data, err = os.ReadFile(bzName)
+	require.NoError(t, err)
+	assert.Equal(t, testBzip2YAML, data, "the file must not be modified")
+}
+
+func TestIncludes_CompressedMaxBytes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml": &fstest.MapFile{Data: []byte("db: !include db.yaml.gz\n")},
+		"db.yaml.gz":  &fstest.MapFile{Data: testGzip(t, "host: "+strings.Repeat("a", 4096)+"\n")},
+	}
+	require.Less(t, len(fsys["db.yaml.gz"].Data), 256)
+
+	_, err := FromFS[map[string]any](fsys, "config.yaml", Includes(fsys), MaxBytes(256))
+	var sizeErr *SizeLimitError
+	require.ErrorAs(t, err, &sizeErr)
+	assert.Equal(t, int64(256), sizeErr.Limit)
+}
@@ -0,0 +1,132 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// SetDefaults sets every field of the struct pointed to by v that carries a
+// `default:"..."` struct tag to its default value. Nested structs are
+// filled as well, while nil pointers are left untouched.
+//
+// The loaders apply the same tags automatically: fields missing from the
+// decoded document receive their default, including fields of structs
+// inside slices and maps, while values present in the document are kept
+// even when they are zero, e.g. `retries: 0`.
+func SetDefaults(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cannot set defaults on %T: not a pointer", v)
+	}
+	var errs []error
+	applyDefaults(rv.Elem(), nil, false, "json", "", &errs)
+	return errors.Join(errs...)
+}
+
+// setDocumentDefaults applies default tags to the fields of a decoded value
+// that are missing from the generic document it was decoded from.
+func setDocumentDefaults(v any, doc any, tag string) error {
+	rv := reflect.ValueOf(v)
+	if !hasDefaults(rv.Type()) {
+		return nil
+	}
+	var errs []error
+	applyDefaults(rv.Elem(), doc, true, tag, "", &errs)
+	return errors.Join(errs...)
+}
+
+// applyDefaults walks a value alongside the document it was decoded from.
+// present reports whether the value appeared in the document at all.
+func applyDefaults(v reflect.Value, doc any, present bool, tag string, path string, errs *[]error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			applyDefaults(v.Elem(), doc, present, tag, path, errs)
+		}
+	case reflect.Struct:
+		if hasCustomUnmarshaler(v.Type()) {
+			return
+		}
+		fields := structFields(v.Type(), tag)
+		seen := map[string]any{}
+		forEachEntry(doc, func(key string, value any) {
+			if f, ok := lookupField(fields, key, tag); ok {
+				seen[f.key] = value
+			}
+		})
+		for _, f := range fields {
+			if f.inline {
+				continue
+			}
+			field := v.FieldByIndex(f.index)
+			fieldPath := joinPath(path, f.key)
+			value, ok := seen[f.key]
+			if def, hasDefault := f.field.Tag.Lookup("default"); hasDefault && !ok {
+				if err := setFromString(field, def); err != nil {
+					*errs = append(*errs, fmt.Errorf("invalid default for %s: %w", fieldPath, err))
+				}
+				continue
+			}
+			applyDefaults(field, value, ok, tag, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		items, _ := doc.([]any)
+		for i := 0; i < v.Len() && i < len(items); i++ {
+			applyDefaults(v.Index(i), items[i], true, tag, indexPath(path, i), errs)
+		}
+	case reflect.Map:
+		if !present || !hasDefaults(v.Type().Elem()) {
+			return
+		}
+		entries := map[string]any{}
+		forEachEntry(doc, func(key string, value any) {
+			entries[key] = value
+		})
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			applyDefaults(elem, entries[key], true, tag, joinPath(path, key), errs)
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+}
+
+var defaultsCache sync.Map
+
+// hasDefaults reports whether a type contains any field with a default
+// tag, so that documents without defaults skip the extra walk.
+func hasDefaults(t reflect.Type) bool {
+	if cached, ok := defaultsCache.Load(t); ok {
+		return cached.(bool)
+	}
+	result := typeHasDefaults(t, map[reflect.Type]bool{})
+	defaultsCache.Store(t, result)
+	return result
+}
+
+func typeHasDefaults(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasDefaults(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if _, ok := f.Tag.Lookup("default"); ok {
+				return true
+			}
+			if typeHasDefaults(f.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
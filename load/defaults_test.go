@@ -0,0 +1,102 @@
+package load
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDefaultsBackend struct {
+	Host    string        `json:"host" yaml:"host"`
+	Port    int           `json:"port" yaml:"port" default:"8080"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout" default:"5s"`
+}
+
+type testDefaultsConfig struct {
+	Name     string                         `json:"name" yaml:"name" default:"service"`
+	Retries  int                            `json:"retries" yaml:"retries" default:"3"`
+	Enabled  bool                           `json:"enabled" yaml:"enabled" default:"true"`
+	Tags     []string                       `json:"tags" yaml:"tags" default:"a,b"`
+	Bind     net.IP                         `json:"bind" yaml:"bind" default:"127.0.0.1"`
+	Primary  testDefaultsBackend            `json:"primary" yaml:"primary"`
+	Backends []testDefaultsBackend          `json:"backends" yaml:"backends"`
+	Zones    map[string]testDefaultsBackend `json:"zones" yaml:"zones"`
+	Fallback *testDefaultsBackend           `json:"fallback" yaml:"fallback"`
+}
+
+func TestDefaults_YAML(t *testing.T) {
+	data := `
+retries: 0
+enabled: false
+backends:
+  - host: a
+  - host: b
+    port: 0
+    timeout: 1s
+zones:
+  eu:
+    host: eu.local
+fallback:
+  host: spare
+`
+	got, err := FromYAML[testDefaultsConfig](strings.NewReader(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, "service", got.Name)
+	assert.Equal(t, 0, got.Retries)
+	assert.False(t, got.Enabled)
+	assert.Equal(t, []string{"a", "b"}, got.Tags)
+	assert.Equal(t, net.ParseIP("127.0.0.1"), got.Bind)
+	assert.Equal(t, testDefaultsBackend{Port: 8080, Timeout: 5 * time.Second}, got.Primary)
+	assert.Equal(t, []testDefaultsBackend{
+		{Host: "a", Port: 8080, Timeout: 5 * time.Second},
+		{Host: "b", Port: 0, Timeout: time.Second},
+	}, got.Backends)
+	assert.Equal(t, testDefaultsBackend{Host: "eu.local", Port: 8080, Timeout: 5 * time.Second}, got.Zones["eu"])
+	assert.Equal(t, &testDefaultsBackend{Host: "spare", Port: 8080, Timeout: 5 * time.Second}, got.Fallback)
+}
+
+func TestDefaults_JSON(t *testing.T) {
+	got, err := FromJSON[testDefaultsConfig](strings.NewReader(`{"Name":"api","tags":[],"backends":[{"host":"a"}]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "api", got.Name)
+	assert.Equal(t, 3, got.Retries)
+	assert.Empty(t, got.Tags)
+	assert.Equal(t, 8080, got.Backends[0].Port)
+	assert.Nil(t, got.Fallback)
+}
+
+func TestDefaults_YAMLDocuments(t *testing.T) {
+	for backend, err := range YAMLDocuments[testDefaultsBackend](strings.NewReader("host: a\n---\nhost: b\nport: 1\n")) {
+		require.NoError(t, err)
+		assert.NotZero(t, backend.Port)
+		assert.Equal(t, 5*time.Second, backend.Timeout)
+	}
+}
+
+func TestDefaults_InvalidTag(t *testing.T) {
+	type config struct {
+		Port int `json:"port" default:"eighty"`
+	}
+
+	_, err := FromJSON[config](strings.NewReader(`{}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid default for port")
+}
+
+func TestSetDefaults(t *testing.T) {
+	var cfg testDefaultsConfig
+	require.NoError(t, SetDefaults(&cfg))
+
+	assert.Equal(t, "service", cfg.Name)
+	assert.Equal(t, 3, cfg.Retries)
+	assert.Equal(t, 8080, cfg.Primary.Port)
+	assert.Nil(t, cfg.Fallback)
+
+	assert.Error(t, SetDefaults(cfg))
+}
@@ -0,0 +1,314 @@
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeKind classifies a difference between two values.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a single difference between two values.
+type Change struct {
+	Kind ChangeKind
+	// Path locates the value in the style of "servers[0].port", with
+	// items of slices matched by key written as "servers[name=api]".
+	Path string
+	// Pointer is the JSON pointer of the value at the point the change is
+	// applied, so that applying the changes in order turns the old value
+	// into the new one.
+	Pointer string
+	// Old is the removed or replaced value.
+	Old any
+	// New is the added or replacing value.
+	New any
+}
+
+// Changes lists the differences between two values.
+type Changes []Change
+
+// PatchOp is a single JSON Patch (RFC 6902) operation.
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// MarshalJSON writes the value of the operations that take one even when
+// it is null.
+func (op PatchOp) MarshalJSON() ([]byte, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			Op    string `json:"op"`
+			Path  string `json:"path"`
+			Value any    `json:"value"`
+		}{op.Op, op.Path, op.Value})
+	default:
+		type plain PatchOp
+		return json.Marshal(plain{Op: op.Op, Path: op.Path, From: op.From})
+	}
+}
+
+// MatchKey matches the items of slices by the value of a key field, such
+// as "name", instead of by index when diffing. It applies to slices whose
+// items all are mappings holding a distinct scalar value for the key;
+// other slices are still matched by index.
+func MatchKey(key string) Option {
+	return func(o *options) {
+		o.matchKey = key
+	}
+}
+
+// Diff compares two decoded values, such as structs, maps and slices, and
+// returns the paths that were added, removed or changed going from a to b.
+// Values are compared the way ToJSON encodes them, so struct fields are
+// named after their json tags. Changes are ordered so that applying them
+// in order turns a into b.
+func Diff(a, b any, opts ...Option) (Changes, error) {
+	o := newOptions(opts)
+	left, err := genericValue(a)
+	if err != nil {
+		return nil, err
+	}
+	right, err := genericValue(b)
+	if err != nil {
+		return nil, err
+	}
+	d := &differ{key: o.matchKey}
+	d.diff("", "", left, right)
+	return d.changes, nil
+}
+
+// genericValue converts a value into its generic JSON form: maps with
+// string keys, slices and scalars.
+func genericValue(v any) (any, error) {
+	e := &encoder{format: FormatJSON, tag: "json"}
+	tree, err := e.value(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	data, err := marshalJSON(tree)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, and yaml.v3 reads numbers as int or float64.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	return valueFromNode(&node)
+}
+
+// differ collects the changes between two generic values.
+type differ struct {
+	key     string
+	changes Changes
+}
+
+func (d *differ) add(kind ChangeKind, path, pointer string, before, after any) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Pointer: pointer, Old: before, New: after})
+}
+
+// diff compares the values found at a path.
+func (d *differ) diff(path, pointer string, a, b any) {
+	switch left := a.(type) {
+	case map[string]any:
+		if right, ok := b.(map[string]any); ok {
+			d.diffMaps(path, pointer, left, right)
+			return
+		}
+	case []any:
+		if right, ok := b.([]any); ok {
+			if d.key != "" {
+				if leftKeys, ok := itemKeys(left, d.key); ok {
+					if rightKeys, ok := itemKeys(right, d.key); ok {
+						d.diffKeyed(path, pointer, left, right, leftKeys, rightKeys)
+						return
+					}
+				}
+			}
+			d.diffIndexed(path, pointer, left, right)
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		d.add(ChangeChanged, path, pointer, a, b)
+	}
+}
+
+func (d *differ) diffMaps(path, pointer string, a, b map[string]any) {
+	keys := slices.Collect(maps.Keys(a))
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		childPath, childPointer := joinPath(path, key), pointer+jsonPointer([]string{key})
+		left, inA := a[key]
+		right, inB := b[key]
+		switch {
+		case !inB:
+			d.add(ChangeRemoved, childPath, childPointer, left, nil)
+		case !inA:
+			d.add(ChangeAdded, childPath, childPointer, nil, right)
+		default:
+			d.diff(childPath, childPointer, left, right)
+		}
+	}
+}
+
+// diffIndexed compares slices item by item. Removed items are listed from
+// the end so that the pointers of the remaining ones stay valid.
+func (d *differ) diffIndexed(path, pointer string, a, b []any) {
+	common := min(len(a), len(b))
+	for i := 0; i < common; i++ {
+		d.diff(indexPath(path, i), fmt.Sprintf("%s/%d", pointer, i), a[i], b[i])
+	}
+	for i := len(a) - 1; i >= common; i-- {
+		d.add(ChangeRemoved, indexPath(path, i), fmt.Sprintf("%s/%d", pointer, i), a[i], nil)
+	}
+	for i := common; i < len(b); i++ {
+		d.add(ChangeAdded, indexPath(path, i), fmt.Sprintf("%s/%d", pointer, i), nil, b[i])
+	}
+}
+
+// diffKeyed compares slices whose items are matched by key. Items only
+// found in b are appended, so the order of the items is not compared.
+func (d *differ) diffKeyed(path, pointer string, a, b []any, aKeys, bKeys map[string]int) {
+	keyPath := func(item any) string {
+		return fmt.Sprintf("%s[%s=%v]", path, d.key, item.(map[string]any)[d.key])
+	}
+	for i, item := range a {
+		if j, ok := bKeys[itemKey(item, d.key)]; ok {
+			d.diff(keyPath(item), fmt.Sprintf("%s/%d", pointer, i), item, b[j])
+		}
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		if _, ok := bKeys[itemKey(a[i], d.key)]; !ok {
+			d.add(ChangeRemoved, keyPath(a[i]), fmt.Sprintf("%s/%d", pointer, i), a[i], nil)
+		}
+	}
+	for _, item := range b {
+		if _, ok := aKeys[itemKey(item, d.key)]; !ok {
+			d.add(ChangeAdded, keyPath(item), pointer+"/-", nil, item)
+		}
+	}
+}
+
+// itemKeys indexes the items of a slice by the value of their key field,
+// reporting false when an item is not a mapping with a distinct scalar
+// value for the key.
+func itemKeys(items []any, key string) (map[string]int, bool) {
+	keys := make(map[string]int, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		switch m[key].(type) {
+		case string, int, int64, uint64, float64, bool:
+		default:
+			return nil, false
+		}
+		k := itemKey(item, key)
+		if _, ok := keys[k]; ok {
+			return nil, false
+		}
+		keys[k] = i
+	}
+	return keys, true
+}
+
+// itemKey returns the key of a slice item matched by key.
+func itemKey(item any, key string) string {
+	value := item.(map[string]any)[key]
+	return fmt.Sprintf("%T:%v", value, value)
+}
+
+// JSONPatch converts the changes into JSON Patch operations.
+func (c Changes) JSONPatch() []PatchOp {
+	ops := make([]PatchOp, 0, len(c))
+	for _, change := range c {
+		switch change.Kind {
+		case ChangeAdded:
+			ops = append(ops, PatchOp{Op: "add", Path: change.Pointer, Value: change.New})
+		case ChangeRemoved:
+			ops = append(ops, PatchOp{Op: "remove", Path: change.Pointer})
+		case ChangeChanged:
+			ops = append(ops, PatchOp{Op: "replace", Path: change.Pointer, Value: change.New})
+		}
+	}
+	return ops
+}
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// Render writes the changes as a human-readable diff, one line per change:
+// "+" for added, "-" for removed and "~" for changed values. Lines are
+// coloured with ANSI escape codes when colored is true.
+func (c Changes) Render(w io.Writer, colored bool) error {
+	var buf bytes.Buffer
+	for _, change := range c {
+		path := change.Path
+		if path == "" {
+			path = "(root)"
+		}
+		var line, color string
+		switch change.Kind {
+		case ChangeAdded:
+			line, color = fmt.Sprintf("+ %s: %s", path, diffText(change.New)), ansiGreen
+		case ChangeRemoved:
+			line, color = fmt.Sprintf("- %s: %s", path, diffText(change.Old)), ansiRed
+		default:
+			line, color = fmt.Sprintf("~ %s: %s -> %s", path, diffText(change.Old), diffText(change.New)), ansiYellow
+		}
+		if colored {
+			line = color + line + ansiReset
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// String renders the changes without colours.
+func (c Changes) String() string {
+	var b strings.Builder
+	_ = c.Render(&b, false)
+	return b.String()
+}
+
+// diffText renders a value of a change as compact JSON.
+func diffText(v any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,133 @@
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDiffServer struct {
+	Name    string `json:"name"`
+	Port    int    `json:"port"`
+	Enabled bool   `json:"enabled"`
+}
+
+type testDiffConfig struct {
+	Version int               `json:"version"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Servers []testDiffServer  `json:"servers"`
+	Tags    []string          `json:"tags"`
+}
+
+func testDiffConfigs() (testDiffConfig, testDiffConfig) {
+	a := testDiffConfig{
+		Version: 1,
+		Labels:  map[string]string{"team": "core", "tier": "web"},
+		Servers: []testDiffServer{{Name: "a", Port: 80, Enabled: true}, {Name: "b", Port: 81}},
+		Tags:    []string{"x", "y", "z"},
+	}
+	b := testDiffConfig{
+		Version: 2,
+		Labels:  map[string]string{"tier": "api", "owner": "ops"},
+		Servers: []testDiffServer{{Name: "c", Port: 82}, {Name: "a", Port: 8080, Enabled: true}},
+		Tags:    []string{"x"},
+	}
+	return a, b
+}
+
+func TestDiff(t *testing.T) {
+	a, b := testDiffConfigs()
+	changes, err := Diff(a, b)
+	require.NoError(t, err)
+
+	assert.Equal(t, Changes{
+		{Kind: ChangeAdded, Path: "labels.owner", Pointer: "/labels/owner", New: "ops"},
+		{Kind: ChangeRemoved, Path: "labels.team", Pointer: "/labels/team", Old: "core"},
+		{Kind: ChangeChanged, Path: "labels.tier", Pointer: "/labels/tier", Old: "web", New: "api"},
+		{Kind: ChangeChanged, Path: "servers[0].enabled", Pointer: "/servers/0/enabled", Old: true, New: false},
+		{Kind: ChangeChanged, Path: "servers[0].name", Pointer: "/servers/0/name", Old: "a", New: "c"},
+		{Kind: ChangeChanged, Path: "servers[0].port", Pointer: "/servers/0/port", Old: 80, New: 82},
+		{Kind: ChangeChanged, Path: "servers[1].enabled", Pointer: "/servers/1/enabled", Old: false, New: true},
+		{Kind: ChangeChanged, Path: "servers[1].name", Pointer: "/servers/1/name", Old: "b", New: "a"},
+		{Kind: ChangeChanged, Path: "servers[1].port", Pointer: "/servers/1/port", Old: 81, New: 8080},
+		{Kind: ChangeRemoved, Path: "tags[2]", Pointer: "/tags/2", Old: "z"},
+		{Kind: ChangeRemoved, Path: "tags[1]", Pointer: "/tags/1", Old: "y"},
+		{Kind: ChangeChanged, Path: "version", Pointer: "/version", Old: 1, New: 2},
+	}, changes)
+}
+
+func TestDiff_MatchKey(t *testing.T) {
+	a, b := testDiffConfigs()
+	changes, err := Diff(a.Servers, b.Servers, MatchKey("name"))
+	require.NoError(t, err)
+
+	assert.Equal(t, Changes{
+		{Kind: ChangeChanged, Path: "[name=a].port", Pointer: "/0/port", Old: 80, New: 8080},
+		{Kind: ChangeRemoved, Path: "[name=b]", Pointer: "/1", Old: map[string]any{"name": "b", "port": 81, "enabled": false}},
+		{Kind: ChangeAdded, Path: "[name=c]", Pointer: "/-", New: map[string]any{"name": "c", "port": 82, "enabled": false}},
+	}, changes)
+
+	// Slices without the key field fall back to matching by index.
+	changes, err = Diff([]string{"a"}, []string{"b"}, MatchKey("name"))
+	require.NoError(t, err)
+	assert.Equal(t, Changes{{Kind: ChangeChanged, Path: "[0]", Pointer: "/0", Old: "a", New: "b"}}, changes)
+}
+
+func TestDiff_Equal(t *testing.T) {
+	a, _ := testDiffConfigs()
+	changes, err := Diff(a, &a)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = Diff(map[string]any{"port": 80}, map[string]int{"port": 80})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestChanges_Render(t *testing.T) {
+	changes, err := Diff(
+		map[string]any{"name": "api", "port": 80, "tags": []string{"a"}},
+		map[string]any{"name": "api", "port": 8080, "owner": map[string]string{"team": "ops"}},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, `+ owner: {"team":"ops"}
+~ port: 80 -> 8080
+- tags: ["a"]
+`, changes.String())
+
+	var buf bytes.Buffer
+	require.NoError(t, changes.Render(&buf, true))
+	assert.Equal(t, "\x1b[32m+ owner: {\"team\":\"ops\"}\x1b[0m\n\x1b[33m~ port: 80 -> 8080\x1b[0m\n\x1b[31m- tags: [\"a\"]\x1b[0m\n", buf.String())
+
+	root, err := Diff(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "~ (root): 1 -> 2\n", root.String())
+}
+
+func TestChanges_JSONPatch(t *testing.T) {
+	a, b := testDiffConfigs()
+	changes, err := Diff(a, b, MatchKey("name"))
+	require.NoError(t, err)
+
+	data, err := json.Marshal(changes.JSONPatch())
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op": "add", "path": "/labels/owner", "value": "ops"},
+		{"op": "remove", "path": "/labels/team"},
+		{"op": "replace", "path": "/labels/tier", "value": "api"},
+		{"op": "replace", "path": "/servers/0/port", "value": 8080},
+		{"op": "remove", "path": "/servers/1"},
+		{"op": "add", "path": "/servers/-", "value": {"name": "c", "port": 82, "enabled": false}},
+		{"op": "remove", "path": "/tags/2"},
+		{"op": "remove", "path": "/tags/1"},
+		{"op": "replace", "path": "/version", "value": 2}
+	]`, string(data))
+
+	data, err = json.Marshal(PatchOp{Op: "replace", Path: "/a", Value: nil})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"op": "replace", "path": "/a", "value": null}`, string(data))
+}
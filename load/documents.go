@@ -0,0 +1,186 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentKind describes how to decode the documents of a single kind
+// in a multi-document YAML stream.
+type DocumentKind struct {
+	decode func(node *yaml.Node, o *options) (any, error)
+}
+
+// Kind registers T as the Go type that documents of a kind decode into.
+func Kind[T any]() DocumentKind {
+	return DocumentKind{
+		decode: func(node *yaml.Node, o *options) (any, error) {
+			var v T
+			if err := decodeNode(node, &v, o); err != nil {
+				return nil, err
+			}
+			return v, nil
+		},
+	}
+}
+
+// Kinds maps the value of a discriminator field to the type its documents
+// decode into.
+type Kinds map[string]DocumentKind
+
+// YAMLDocuments lazily decodes every document of a "---"-separated YAML
+// stream into T. Errors for a single document are yielded without ending
+// the iteration, while syntax errors end it since the stream cannot be
+// resynchronised.
+func YAMLDocuments[T any](data io.Reader, opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for node, err := range yamlNodes(data, newOptions(opts).maxBytes) {
+			var v T
+			if err == nil {
+				if err = decodeNode(node, &v, newOptions(opts)); err != nil {
+					err = fmt.Errorf("document at line %d: %w", node.Line, err)
+				}
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// YAMLDocumentsByKind lazily decodes every document of a YAML stream into
+// the type registered for the value of its discriminator field, such as
+// the "kind" field of Kubernetes manifests.
+func YAMLDocumentsByKind(data io.Reader, discriminator string, kinds Kinds, opts ...Option) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		for node, err := range yamlNodes(data, newOptions(opts).maxBytes) {
+			var v any
+			if err == nil {
+				if v, err = decodeKind(node, discriminator, kinds, newOptions(opts)); err != nil {
+					err = fmt.Errorf("document at line %d: %w", node.Line, err)
+				}
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// decodeKind dispatches a single document to the decoder of its kind.
+func decodeKind(node *yaml.Node, discriminator string, kinds Kinds, o *options) (any, error) {
+	value := mappingValue(node, discriminator)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("missing %q field", discriminator)
+	}
+	kind, ok := kinds[value.Value]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s %q", discriminator, value.Value)
+	}
+	return kind.decode(node, o)
+}
+
+// yamlNodes iterates over the non-empty documents of a YAML stream of at
+// most maxBytes bytes.
+func yamlNodes(data io.Reader, maxBytes int64) iter.Seq2[*yaml.Node, error] {
+	return func(yield func(*yaml.Node, error) bool) {
+		reader := newLimitedReader(data, maxBytes)
+		dec := yaml.NewDecoder(reader)
+		for i := 0; ; i++ {
+			var node yaml.Node
+			err := dec.Decode(&node)
+			if reader.exceeded {
+				// yaml.v3 reports read errors as plain text.
+				err = reader.err()
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("document %d: %w", i, err))
+				return
+			}
+			if isEmptyDocument(&node) {
+				continue
+			}
+			if !yield(&node, nil) {
+				return
+			}
+		}
+	}
+}
+
+// decodeNode decodes a YAML node into v according to the provided options.
+func decodeNode(node *yaml.Node, v any, o *options) error {
+	if err := checkLimits(node, o); err != nil {
+		return err
+	}
+	secrets, err := transformNode(node, o)
+	if err != nil {
+		return err
+	}
+	if err := checkSchema(node, o); err != nil {
+		return err
+	}
+	if o.strict {
+		var doc any
+		if err := node.Decode(&doc); err != nil {
+			return yamlDecodeError(node, err)
+		}
+		if err := checkUnknownFields(doc, reflect.TypeOf(v).Elem(), "yaml"); err != nil {
+			return err
+		}
+	}
+	if err := node.Decode(v); err != nil {
+		return yamlDecodeError(node, err)
+	}
+	if err := sealSecrets(node, reflect.ValueOf(v), secrets, "yaml", ""); err != nil {
+		return err
+	}
+	if hasDefaults(reflect.TypeOf(v)) {
+		var doc any
+		if err := node.Decode(&doc); err != nil {
+			return err
+		}
+		if err := setDocumentDefaults(v, doc, "yaml"); err != nil {
+			return err
+		}
+	}
+	if o.validate {
+		return validate(v, "yaml")
+	}
+	return nil
+}
+
+// mappingValue returns the value node stored under a key of a mapping
+// node, unwrapping document nodes.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	node = documentContent(node)
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// isEmptyDocument reports whether a decoded document holds no content,
+// as produced by consecutive or trailing "---" separators.
+func isEmptyDocument(node *yaml.Node) bool {
+	if node.Kind == 0 {
+		return true
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return node.Kind == yaml.DocumentNode
+	}
+	content := node.Content[0]
+	return content.Kind == yaml.ScalarNode && content.Tag == "!!null" && content.Value == ""
+}
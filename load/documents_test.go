@@ -0,0 +1,131 @@
+package load
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDeployment struct {
+	Kind     string `yaml:"kind"`
+	Name     string `yaml:"name"`
+	Replicas int    `yaml:"replicas"`
+}
+
+type testConfigMap struct {
+	Kind string            `yaml:"kind"`
+	Name string            `yaml:"name"`
+	Data map[string]string `yaml:"data"`
+}
+
+func TestYAMLDocuments(t *testing.T) {
+	data := "---\nname: Ada\nage: 42\n---\n---\nname: Bob\nage: 7\n...\n---\nname: Eve\n"
+
+	var got []testPerson
+	for person, err := range YAMLDocuments[testPerson](strings.NewReader(data)) {
+		require.NoError(t, err)
+		got = append(got, person)
+	}
+
+	assert.Equal(t, []testPerson{
+		{Name: "Ada", Age: 42},
+		{Name: "Bob", Age: 7},
+		{Name: "Eve"},
+	}, got)
+}
+
+func TestYAMLDocuments_DocumentErrorContinues(t *testing.T) {
+	data := "name: Ada\n---\nname: Bob\nage: old\n---\nname: Eve\n"
+
+	var names []string
+	var errs []error
+	for person, err := range YAMLDocuments[testPerson](strings.NewReader(data)) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		names = append(names, person.Name)
+	}
+
+	assert.Equal(t, []string{"Ada", "Eve"}, names)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "document at line 2")
+}
+
+func TestYAMLDocuments_SyntaxErrorStops(t *testing.T) {
+	data := "name: Ada\n---\nname: [unclosed\n---\nname: Eve\n"
+
+	var count int
+	var lastErr error
+	for _, err := range YAMLDocuments[testPerson](strings.NewReader(data)) {
+		count++
+		lastErr = err
+	}
+
+	assert.Equal(t, 2, count)
+	assert.Error(t, lastErr)
+}
+
+func TestYAMLDocuments_Strict(t *testing.T) {
+	data := "name: Ada\nnmae: typo\n"
+
+	for _, err := range YAMLDocuments[testPerson](strings.NewReader(data), Strict()) {
+		var unknown *UnknownFieldsError
+		require.ErrorAs(t, err, &unknown)
+		assert.Equal(t, []string{"nmae"}, unknown.Paths)
+	}
+}
+
+func TestYAMLDocuments_EarlyBreak(t *testing.T) {
+	data := "name: Ada\n---\nname: Bob\n---\nname: Eve\n"
+
+	var got []string
+	for person := range YAMLDocuments[testPerson](strings.NewReader(data)) {
+		got = append(got, person.Name)
+		if len(got) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"Ada", "Bob"}, got)
+}
+
+func TestYAMLDocumentsByKind(t *testing.T) {
+	data := `kind: Deployment
+name: web
+replicas: 3
+---
+kind: ConfigMap
+name: settings
+data:
+  mode: fast
+---
+kind: Secret
+name: token
+---
+name: orphan
+`
+	kinds := Kinds{
+		"Deployment": Kind[testDeployment](),
+		"ConfigMap":  Kind[testConfigMap](),
+	}
+
+	var docs []any
+	var errs []error
+	for doc, err := range YAMLDocumentsByKind(strings.NewReader(data), "kind", kinds) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	require.Len(t, docs, 2)
+	assert.Equal(t, testDeployment{Kind: "Deployment", Name: "web", Replicas: 3}, docs[0])
+	assert.Equal(t, testConfigMap{Kind: "ConfigMap", Name: "settings", Data: map[string]string{"mode": "fast"}}, docs[1])
+
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), `unknown kind "Secret"`)
+	assert.Contains(t, errs[1].Error(), `missing "kind" field`)
+}
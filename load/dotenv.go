@@ -0,0 +1,255 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// dotenvKeyPattern matches the variable names accepted in .env files.
+var dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// Dotenv holds the variables of a .env file in the order they are first
+// defined.
+type Dotenv struct {
+	keys   []string
+	values map[string]string
+}
+
+// FromDotenv parses a .env file the way docker-compose does:
+//
+//   - KEY=value pairs, optionally prefixed with `export`
+//   - blank lines and lines starting with # are skipped
+//   - unquoted values end at the end of the line or at a # preceded by
+//     whitespace, and are trimmed
+//   - single-quoted values are taken literally
+//   - double-quoted values support the \n, \r, \t, \\, \" and \$ escapes
+//   - quoted values may span multiple lines
+//
+// Unquoted and double-quoted values expand $VAR references as well as
+// ${VAR} references in the forms supported by ExpandEnv. References
+// resolve to the keys defined earlier in the file, then to the lookup
+// provided with ExpandEnv. A line holding only a key takes its value from
+// that lookup. Syntax errors are reported as a *LineError.
+func FromDotenv(r io.Reader, opts ...Option) (*Dotenv, error) {
+	o := newOptions(opts)
+	data, err := readAll(r, o)
+	if err != nil {
+		return nil, err
+	}
+	return parseDotenv(string(data), o.lookupEnv)
+}
+
+// FromDotenvFS parses the .env file at the given path inside the provided
+// filesystem, such as the root directory injected into a command context
+// by fileutils.ApplyRootDirToContext.
+func FromDotenvFS(fsys fs.FS, name string, opts ...Option) (*Dotenv, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d, err := FromDotenv(f, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// Keys returns the variable names in the order they are defined.
+func (d *Dotenv) Keys() []string {
+	return slices.Clone(d.keys)
+}
+
+// Len returns the number of variables.
+func (d *Dotenv) Len() int {
+	return len(d.keys)
+}
+
+// Lookup returns the value of a variable, reporting whether it is defined.
+// It satisfies LookupFunc, so the file can be bound with FromEnv directly.
+func (d *Dotenv) Lookup(key string) (string, bool) {
+	value, ok := d.values[key]
+	return value, ok
+}
+
+// All iterates over the variables in the order they are defined.
+func (d *Dotenv) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, key := range d.keys {
+			if !yield(key, d.values[key]) {
+				return
+			}
+		}
+	}
+}
+
+// Overlay returns a LookupFunc that resolves variables through base, or
+// os.LookupEnv when it is nil, and falls back to the variables of the
+// file. As with docker-compose, the environment takes precedence over the
+// .env file. Pass the result to FromEnv or ExpandEnv.
+func (d *Dotenv) Overlay(base LookupFunc) LookupFunc {
+	if base == nil {
+		base = os.LookupEnv
+	}
+	return func(name string) (string, bool) {
+		if value, ok := base(name); ok {
+			return value, true
+		}
+		return d.Lookup(name)
+	}
+}
+
+// Apply sets the variables of the file in the process environment, leaving
+// the variables that are already set untouched.
+func (d *Dotenv) Apply() error {
+	var errs []error
+	for key, value := range d.All() {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// set defines a variable, keeping the position of a redefined one.
+func (d *Dotenv) set(key, value string) {
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = value
+}
+
+// parseDotenv parses the content of a .env file.
+func parseDotenv(data string, lookup LookupFunc) (*Dotenv, error) {
+	d := &Dotenv{values: map[string]string{}}
+	resolve := func(name string) (string, bool) {
+		if value, ok := d.Lookup(name); ok {
+			return value, true
+		}
+		if lookup != nil {
+			return lookup(name)
+		}
+		return "", false
+	}
+
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		// Trailing whitespace may belong to a multi-line quoted value.
+		line := strings.TrimLeft(lines[i], " \t")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && strings.TrimSpace(rest) != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimLeft(rest, " \t")
+		}
+
+		key, rest, hasValue := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !dotenvKeyPattern.MatchString(key) {
+			return nil, &LineError{Line: lineNo, Err: fmt.Errorf("invalid variable name %q", key)}
+		}
+		if !hasValue {
+			if value, ok := resolve(key); ok {
+				d.set(key, value)
+			}
+			continue
+		}
+
+		rest = strings.TrimLeft(rest, " \t")
+		var value string
+		var err error
+		if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+			quote := rest[0]
+			body := rest[1:]
+			end := closingQuote(body, quote)
+			for end < 0 {
+				if i+1 >= len(lines) {
+					return nil, &LineError{Line: lineNo, Err: errors.New("unterminated quoted value")}
+				}
+				i++
+				body += "\n" + lines[i]
+				end = closingQuote(body, quote)
+			}
+			if trailing := strings.TrimSpace(body[end+1:]); trailing != "" && trailing[0] != '#' {
+				return nil, &LineError{Line: i + 1, Err: fmt.Errorf("unexpected %q after quoted value", trailing)}
+			}
+			value = body[:end]
+			if quote == '"' {
+				value, err = interpolate(unescapeDotenv(value), resolve, true)
+			}
+		} else {
+			value, err = interpolate(stripInlineComment(rest), resolve, true)
+		}
+		if err != nil {
+			return nil, &LineError{Line: lineNo, Err: fmt.Errorf("%s: %w", key, err)}
+		}
+		d.set(key, value)
+	}
+	return d, nil
+}
+
+// closingQuote returns the position of the quote closing a quoted value,
+// or -1. Double quotes can be escaped with a backslash.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDotenv resolves the escapes of a double-quoted value. Escaped
+// dollar signs become "$$" so that interpolation keeps them literal.
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '\\', '"':
+			b.WriteByte(s[i])
+		case '$':
+			b.WriteString("$$")
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// stripInlineComment removes a comment from an unquoted value, along with
+// the surrounding whitespace.
+func stripInlineComment(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(s)
+}
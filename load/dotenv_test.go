@@ -0,0 +1,158 @@
+package load
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDotenv = `# Project settings
+export APP_NAME=api
+APP_PORT = 8080   # inline comment
+APP_URL=http://localhost:${APP_PORT}/v1
+APP_LITERAL='${APP_PORT} stays # as is'
+APP_GREETING="hello\n\"world\" \$HOME ${APP_NAME}"
+APP_EMPTY=
+APP_HASH=a#b
+APP_CERT="-----BEGIN-----
+line one
+-----END-----"
+APP_MULTI='first
+second'
+APP_FALLBACK=${UNSET:-fallback}
+APP_SHELL
+`
+
+func TestFromDotenv(t *testing.T) {
+	env, err := FromDotenv(strings.NewReader(testDotenv), ExpandEnv(testLookup(map[string]string{"APP_SHELL": "zsh"})))
+	require.NoError(t, err)
+
+	want := map[string]string{
+		"APP_NAME":     "api",
+		"APP_PORT":     "8080",
+		"APP_URL":      "http://localhost:8080/v1",
+		"APP_LITERAL":  "${APP_PORT} stays # as is",
+		"APP_GREETING": "hello\n\"world\" $HOME api",
+		"APP_EMPTY":    "",
+		"APP_HASH":     "a#b",
+		"APP_CERT":     "-----BEGIN-----\nline one\n-----END-----",
+		"APP_MULTI":    "first\nsecond",
+		"APP_FALLBACK": "fallback",
+		"APP_SHELL":    "zsh",
+	}
+	got := map[string]string{}
+	for key, value := range env.All() {
+		got[key] = value
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, []string{
+		"APP_NAME", "APP_PORT", "APP_URL", "APP_LITERAL", "APP_GREETING", "APP_EMPTY",
+		"APP_HASH", "APP_CERT", "APP_MULTI", "APP_FALLBACK", "APP_SHELL",
+	}, env.Keys())
+	assert.Equal(t, 11, env.Len())
+}
+
+func TestFromDotenv_QuotedWhitespace(t *testing.T) {
+	env, err := FromDotenv(strings.NewReader("A=\"first  \n  second \"  \nB='one\t\ntwo'\n  export C=3  \n"))
+	require.NoError(t, err)
+
+	a, _ := env.Lookup("A")
+	assert.Equal(t, "first  \n  second ", a)
+	b, _ := env.Lookup("B")
+	assert.Equal(t, "one\t\ntwo", b)
+	c, _ := env.Lookup("C")
+	assert.Equal(t, "3", c)
+}
+
+func TestFromDotenv_BareReferences(t *testing.T) {
+	input := "HOST=db\nPORT=5432\nURL=postgres://$HOST:$PORT/app\nQUOTED=\"$HOST-${PORT}\"\nLITERAL='$HOST'\nPRICE=5$ and $1 and $$HOST\nSHELL_DIR=$HOME/bin\n"
+	env, err := FromDotenv(strings.NewReader(input), ExpandEnv(testLookup(map[string]string{"HOME": "/home/dev"})))
+	require.NoError(t, err)
+
+	for key, want := range map[string]string{
+		"URL":       "postgres://db:5432/app",
+		"QUOTED":    "db-5432",
+		"LITERAL":   "$HOST",
+		"PRICE":     "5$ and $1 and $HOST",
+		"SHELL_DIR": "/home/dev/bin",
+	} {
+		got, ok := env.Lookup(key)
+		require.True(t, ok, key)
+		assert.Equal(t, want, got, key)
+	}
+}
+
+func TestFromDotenv_Redefined(t *testing.T) {
+	env, err := FromDotenv(strings.NewReader("A=1\nB=2\nA=${A}${B}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "B"}, env.Keys())
+	value, ok := env.Lookup("A")
+	assert.True(t, ok)
+	assert.Equal(t, "12", value)
+}
+
+func TestFromDotenv_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "InvalidKey", input: "A=1\n1BAD=x\n", wantErr: `line 2: invalid variable name "1BAD"`},
+		{name: "Unterminated", input: "A=\"open\nB=2\n", wantErr: "line 1: unterminated quoted value"},
+		{name: "TrailingData", input: "A='x' y\n", wantErr: `line 1: unexpected "y" after quoted value`},
+		{name: "RequiredVariable", input: "A=${MISSING:?must be set}\n", wantErr: "line 1: A: undefined variable MISSING: must be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromDotenv(strings.NewReader(tt.input))
+			var lineErr *LineError
+			assert.ErrorAs(t, err, &lineErr)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFromDotenvFS(t *testing.T) {
+	fsys := fstest.MapFS{".env": {Data: []byte("APP_NAME=api\r\nAPP_DEBUG=true\r\n")}}
+	env, err := FromDotenvFS(fsys, ".env")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"APP_NAME", "APP_DEBUG"}, env.Keys())
+
+	_, err = FromDotenvFS(fstest.MapFS{".env": {Data: []byte("=x\n")}}, ".env")
+	assert.ErrorContains(t, err, "failed to load .env: line 1")
+}
+
+func TestDotenv_Overlay(t *testing.T) {
+	env, err := FromDotenv(strings.NewReader("APP_NAME=from-file\nAPP_DEBUG=true\nAPP_TIMEOUT=5s\n"))
+	require.NoError(t, err)
+
+	type config struct {
+		Name    string
+		Debug   bool
+		Timeout string
+	}
+	lookup := env.Overlay(testLookup(map[string]string{"APP_NAME": "from-env"}))
+	got, err := FromEnv[config]("APP", lookup)
+	require.NoError(t, err)
+	assert.Equal(t, config{Name: "from-env", Debug: true, Timeout: "5s"}, got)
+
+	got, err = FromEnv[config]("APP", env.Lookup)
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", got.Name)
+}
+
+func TestDotenv_Apply(t *testing.T) {
+	t.Setenv("DOTENV_TEST_SET", "kept")
+	env, err := FromDotenv(strings.NewReader("DOTENV_TEST_SET=replaced\nDOTENV_TEST_NEW=added\n"))
+	require.NoError(t, err)
+	t.Setenv("DOTENV_TEST_NEW", "")
+	require.NoError(t, os.Unsetenv("DOTENV_TEST_NEW"))
+
+	require.NoError(t, env.Apply())
+	assert.Equal(t, "kept", os.Getenv("DOTENV_TEST_SET"))
+	assert.Equal(t, "added", os.Getenv("DOTENV_TEST_NEW"))
+}
@@ -0,0 +1,474 @@
+package load
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Indent sets the number of spaces used to indent nested values when
+// writing documents, 2 by default and 0 for TOML tables. An indent of 0
+// writes JSON on a single line, while YAML requires between 2 and 9.
+func Indent(spaces int) Option {
+	return func(o *options) {
+		o.indent, o.hasIndent = spaces, true
+	}
+}
+
+// SortKeys writes the fields of structs sorted by key instead of in
+// declaration order. Map keys are always sorted.
+func SortKeys() Option {
+	return func(o *options) {
+		o.sortKeys = true
+	}
+}
+
+// OmitZero leaves out empty struct fields, as if every field were tagged
+// with omitempty.
+func OmitZero() Option {
+	return func(o *options) {
+		o.omitZero = true
+	}
+}
+
+// MultiDocument makes ToYAML write each element of a slice or array as a
+// separate document of a YAML stream.
+func MultiDocument() Option {
+	return func(o *options) {
+		o.multiDocument = true
+	}
+}
+
+var (
+	yamlMarshalerType = reflect.TypeFor[yaml.Marshaler]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	yamlNodeType      = reflect.TypeFor[yaml.Node]()
+	anyType           = reflect.TypeFor[any]()
+)
+
+// ToJSON writes v to w as a JSON document. Struct fields are named after
+// their `json` tags and written in declaration order unless SortKeys is
+// provided.
+func ToJSON(w io.Writer, v any, opts ...Option) error {
+	return encode(FormatJSON, w, v, opts)
+}
+
+// ToYAML writes v to w as a YAML document, or as a stream of documents
+// with MultiDocument. Struct fields are named after their `yaml` tags and
+// written in declaration order unless SortKeys is provided.
+func ToYAML(w io.Writer, v any, opts ...Option) error {
+	return encode(FormatYAML, w, v, opts)
+}
+
+// ToTOML writes v to w as a TOML document, which must be a table. Struct
+// fields are named after their `toml` tags, and nil values are left out
+// since TOML has no null.
+func ToTOML(w io.Writer, v any, opts ...Option) error {
+	return encode(FormatTOML, w, v, opts)
+}
+
+// encode writes v to w in the given format.
+func encode(format Format, w io.Writer, v any, opts []Option) error {
+	c, err := codecFor(format)
+	if err != nil {
+		return err
+	}
+	o := newOptions(opts)
+	e := &encoder{format: format, tag: c.tag, sortKeys: o.sortKeys, omitZero: o.omitZero}
+	indent := 2
+	if format == FormatTOML {
+		indent = 0
+	}
+	if o.hasIndent {
+		indent = o.indent
+	}
+	if indent < 0 || (format == FormatYAML && (indent < 2 || indent > 9)) {
+		return fmt.Errorf("invalid indent of %d spaces for %s", indent, format)
+	}
+
+	values := []reflect.Value{reflect.ValueOf(v)}
+	if o.multiDocument {
+		if format != FormatYAML {
+			return fmt.Errorf("multi-document output is not supported for %s", format)
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Errorf("cannot write %T as multiple documents: not a slice", v)
+		}
+		values = values[:0]
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, rv.Index(i))
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(indent)
+		for _, value := range values {
+			tree, err := e.value(value)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(tree); err != nil {
+				return err
+			}
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	case FormatJSON:
+		tree, err := e.value(values[0])
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if indent > 0 {
+			enc.SetIndent("", strings.Repeat(" ", indent))
+		}
+		if err := enc.Encode(tree); err != nil {
+			return err
+		}
+	case FormatTOML:
+		tree, err := e.value(values[0])
+		if err != nil {
+			return err
+		}
+		if _, ok := tree.(orderedMap); !ok {
+			return errors.New("TOML documents must be tables")
+		}
+		table, err := tomlValue(tree)
+		if err != nil {
+			return err
+		}
+		enc := toml.NewEncoder(&buf)
+		enc.SetIndentTables(indent > 0)
+		enc.SetIndentSymbol(strings.Repeat(" ", indent))
+		if err := enc.Encode(table); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// encoder names and orders the fields of Go values for one output format,
+// leaving the marshalling of the values themselves to the format's library.
+type encoder struct {
+	format   Format
+	tag      string
+	sortKeys bool
+	omitZero bool
+}
+
+// orderedMap is a mapping that keeps its entries in the order they were
+// added when it is marshalled.
+type orderedMap []mapEntry
+
+type mapEntry struct {
+	key   string
+	value any
+}
+
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJSON(entry.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(entry.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.key, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m orderedMap) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, entry := range m {
+		child := &yaml.Node{}
+		if err := child.Encode(entry.value); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.key, err)
+		}
+		node.Content = append(node.Content, stringNode(entry.key), child)
+	}
+	return node, nil
+}
+
+// marshalJSON encodes a value as JSON without escaping HTML characters.
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// node converts a value into a YAML node tree.
+func (e *encoder) node(v reflect.Value) (*yaml.Node, error) {
+	tree, err := e.value(v)
+	if err != nil {
+		return nil, err
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(tree); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// value converts structs and maps into ordered maps and slices into
+// []any, keeping every other value as is for the library to marshal.
+func (e *encoder) value(v reflect.Value) (any, error) {
+	for {
+		if !v.IsValid() {
+			return nil, nil
+		}
+		if leaf, ok, err := e.leaf(v); ok || err != nil {
+			return leaf, err
+		}
+		if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+			break
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return e.structValue(v)
+	case reflect.Map:
+		return e.mapValue(v)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			if e.format == FormatJSON {
+				return data, nil
+			}
+			// The YAML and TOML decoders read byte slices from plain text.
+			return string(data), nil
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			item, err := e.value(v.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// leaf returns the values that the libraries marshal on their own, such as
+// timestamps and types implementing a marshaler interface.
+func (e *encoder) leaf(v reflect.Value) (any, bool, error) {
+	t := v.Type()
+	switch {
+	case t == durationType, t == timeType:
+		return v.Interface(), true, nil
+	case t == yamlNodeType:
+		node := v.Interface().(yaml.Node)
+		if e.format == FormatYAML {
+			return &node, true, nil
+		}
+		doc, err := valueFromNode(&node)
+		if err != nil {
+			return nil, true, err
+		}
+		value, err := e.value(reflect.ValueOf(doc))
+		return value, true, err
+	}
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, false, nil
+	}
+
+	if m, ok := implementer(v, yamlMarshalerType); ok && e.format == FormatYAML {
+		return m, true, nil
+	}
+	if m, ok := implementer(v, jsonMarshalerType); ok && e.format == FormatJSON {
+		return m, true, nil
+	}
+	if m, ok := implementer(v, textMarshalerType); ok {
+		return m, true, nil
+	}
+	return nil, false, nil
+}
+
+// implementer returns v, or its address for methods with a pointer
+// receiver, when it implements the given interface.
+func implementer(v reflect.Value, iface reflect.Type) (any, bool) {
+	if v.Kind() != reflect.Interface && v.Type().Implements(iface) {
+		return v.Interface(), true
+	}
+	if v.CanAddr() && v.Kind() != reflect.Pointer && reflect.PointerTo(v.Type()).Implements(iface) {
+		return v.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+// structValue converts a struct into an ordered map of its fields. The
+// entries of an inline map follow the fields, as they do with yaml.Marshal.
+func (e *encoder) structValue(v reflect.Value) (orderedMap, error) {
+	fields := structFields(v.Type(), e.tag)
+	m := orderedMap{}
+	var inline orderedMap
+	for _, f := range fields {
+		field, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			// The field is promoted through a nil embedded pointer.
+			continue
+		}
+		_, opts := parseTag(f.field.Tag.Get(e.tag))
+		if (e.omitZero || hasTagOption(opts, "omitempty")) && isEmptyValue(field) {
+			continue
+		}
+		value, err := e.value(field)
+		if err != nil {
+			if f.inline {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: %w", f.key, err)
+		}
+		if f.inline {
+			inline, _ = value.(orderedMap)
+			continue
+		}
+		m = append(m, mapEntry{key: f.key, value: value})
+	}
+	for _, entry := range inline {
+		if _, ok := lookupField(fields, entry.key, e.tag); ok {
+			return nil, fmt.Errorf("cannot have key %q in inlined map: conflicts with struct field", entry.key)
+		}
+	}
+	m = append(m, inline...)
+	if e.sortKeys {
+		slices.SortStableFunc(m, func(a, b mapEntry) int {
+			return strings.Compare(a.key, b.key)
+		})
+	}
+	return m, nil
+}
+
+// mapValue converts a map into an ordered map sorted by key.
+func (e *encoder) mapValue(v reflect.Value) (orderedMap, error) {
+	m := make(orderedMap, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		name := fmt.Sprint(key.Interface())
+		if tm, ok := key.Interface().(encoding.TextMarshaler); ok {
+			text, err := tm.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			name = string(text)
+		}
+		value, err := e.value(iter.Value())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		m = append(m, mapEntry{key: name, value: value})
+	}
+	slices.SortFunc(m, func(a, b mapEntry) int {
+		return strings.Compare(a.key, b.key)
+	})
+	return m, nil
+}
+
+// isEmptyValue reports whether a value is left out by omitempty, following
+// encoding/json: structs are never empty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	default:
+		return false
+	}
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// tomlValue turns the ordered maps of a tree into structs whose fields are
+// declared in the same order, since go-toml writes map keys sorted but
+// struct fields in order. TOML has no null, so nil values are left out.
+func tomlValue(v any) (any, error) {
+	switch val := v.(type) {
+	case orderedMap:
+		fields := make([]reflect.StructField, 0, len(val))
+		values := make([]any, 0, len(val))
+		for _, entry := range val {
+			if entry.value == nil {
+				continue
+			}
+			if entry.key == "" || entry.key == "-" || strings.Contains(entry.key, ",") {
+				return nil, fmt.Errorf("cannot write key %q in TOML", entry.key)
+			}
+			value, err := tomlValue(entry.value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", entry.key, err)
+			}
+			fields = append(fields, reflect.StructField{
+				Name: "F" + strconv.Itoa(len(fields)),
+				Type: anyType,
+				Tag:  reflect.StructTag("toml:" + strconv.Quote(entry.key)),
+			})
+			values = append(values, value)
+		}
+		table := reflect.New(reflect.StructOf(fields)).Elem()
+		for i, value := range values {
+			table.Field(i).Set(reflect.ValueOf(value))
+		}
+		return table.Interface(), nil
+	case []any:
+		items := make([]any, len(val))
+		for i, item := range val {
+			value, err := tomlValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			items[i] = value
+		}
+		return items, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,293 @@
+package load
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEncodedServer struct {
+	Host string `json:"host" yaml:"host" toml:"host"`
+	Port int    `json:"port,omitempty" yaml:"port,omitempty" toml:"port,omitempty"`
+}
+
+type testEncodedConfig struct {
+	Name     string              `json:"name" yaml:"name" toml:"name"`
+	Version  string              `json:"version" yaml:"version" toml:"version"`
+	Debug    bool                `json:"debug" yaml:"debug" toml:"debug"`
+	Ratio    float64             `json:"ratio" yaml:"ratio" toml:"ratio"`
+	Timeout  time.Duration       `json:"timeout" yaml:"timeout" toml:"timeout"`
+	Tags     []string            `json:"tags" yaml:"tags" toml:"tags"`
+	Labels   map[string]string   `json:"labels" yaml:"labels" toml:"labels"`
+	Primary  testEncodedServer   `json:"primary" yaml:"primary" toml:"primary"`
+	Replicas []testEncodedServer `json:"replicas" yaml:"replicas" toml:"replicas"`
+	Owner    *testEncodedServer  `json:"owner" yaml:"owner" toml:"owner"`
+}
+
+var testEncoded = testEncodedConfig{
+	Name:     "api <v2>",
+	Version:  "1.10",
+	Debug:    true,
+	Ratio:    2,
+	Timeout:  90 * time.Second,
+	Tags:     []string{"a", "b"},
+	Labels:   map[string]string{"zone": "eu", "tier": "web"},
+	Primary:  testEncodedServer{Host: "a.example", Port: 80},
+	Replicas: []testEncodedServer{{Host: "b.example"}, {Host: "c.example", Port: 81}},
+}
+
+func TestToYAML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ToYAML(&buf, testEncoded))
+	assert.Equal(t, `name: api <v2>
+version: "1.10"
+debug: true
+ratio: 2
+timeout: 1m30s
+tags:
+  - a
+  - b
+labels:
+  tier: web
+  zone: eu
+primary:
+  host: a.example
+  port: 80
+replicas:
+  - host: b.example
+  - host: c.example
+    port: 81
+owner: null
+`, buf.String())
+}
+
+func TestToYAML_RoundTrip(t *testing.T) {
+	input := `# service
+name: api
+version: "1.10"
+debug: false
+ratio: 0.5
+timeout: 5s
+tags: []
+labels:
+  a: "yes"
+  b: |
+    multi
+    line
+primary:
+  host: a.example
+replicas:
+  - host: b.example
+    port: 81
+owner:
+  host: "123"
+`
+	first, err := FromYAML[testEncodedConfig](strings.NewReader(input))
+	require.NoError(t, err)
+	var out1 bytes.Buffer
+	require.NoError(t, ToYAML(&out1, first))
+	assert.Equal(t, strings.TrimPrefix(input, "# service\n"), out1.String())
+
+	second, err := FromYAML[testEncodedConfig](bytes.NewReader(out1.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	var out2 bytes.Buffer
+	require.NoError(t, ToYAML(&out2, second))
+	assert.Equal(t, out1.String(), out2.String())
+}
+
+func TestToYAML_InlineMap(t *testing.T) {
+	type extended struct {
+		Name  string         `yaml:"name"`
+		Extra map[string]any `yaml:",inline"`
+	}
+	input := "name: a\nfoo: 1\nbar:\n  baz: true\n"
+
+	got, err := FromYAML[extended](strings.NewReader(input))
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, ToYAML(&out, got))
+	assert.Equal(t, "name: a\nbar:\n  baz: true\nfoo: 1\n", out.String())
+
+	again, err := FromYAML[extended](bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, got, again)
+
+	out.Reset()
+	require.NoError(t, ToYAML(&out, got, SortKeys()))
+	assert.Equal(t, "bar:\n  baz: true\nfoo: 1\nname: a\n", out.String())
+
+	err = ToYAML(&out, extended{Name: "a", Extra: map[string]any{"name": "b"}})
+	assert.ErrorContains(t, err, `cannot have key "name" in inlined map`)
+}
+
+func TestToYAML_MultiDocument(t *testing.T) {
+	var buf bytes.Buffer
+	servers := []testEncodedServer{{Host: "a"}, {Host: "b", Port: 2}}
+	require.NoError(t, ToYAML(&buf, servers, MultiDocument()))
+	assert.Equal(t, "host: a\n---\nhost: b\nport: 2\n", buf.String())
+
+	var got []testEncodedServer
+	for v, err := range YAMLDocuments[testEncodedServer](&buf) {
+		require.NoError(t, err)
+		got = append(got, v)
+	}
+	assert.Equal(t, servers, got)
+
+	err := ToYAML(&bytes.Buffer{}, servers[0], MultiDocument())
+	assert.ErrorContains(t, err, "not a slice")
+	err = ToJSON(&bytes.Buffer{}, servers, MultiDocument())
+	assert.ErrorContains(t, err, "not supported for json")
+}
+
+func TestToJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ToJSON(&buf, testEncoded))
+	assert.Equal(t, `{
+  "name": "api <v2>",
+  "version": "1.10",
+  "debug": true,
+  "ratio": 2,
+  "timeout": 90000000000,
+  "tags": [
+    "a",
+    "b"
+  ],
+  "labels": {
+    "tier": "web",
+    "zone": "eu"
+  },
+  "primary": {
+    "host": "a.example",
+    "port": 80
+  },
+  "replicas": [
+    {
+      "host": "b.example"
+    },
+    {
+      "host": "c.example",
+      "port": 81
+    }
+  ],
+  "owner": null
+}
+`, buf.String())
+
+	got, err := FromJSON[testEncodedConfig](&buf)
+	require.NoError(t, err)
+	assert.Equal(t, testEncoded, got)
+}
+
+func TestToTOML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ToTOML(&buf, testEncoded))
+	assert.Equal(t, `name = 'api <v2>'
+version = '1.10'
+debug = true
+ratio = 2.0
+timeout = 90000000000
+tags = ['a', 'b']
+
+[labels]
+tier = 'web'
+zone = 'eu'
+
+[primary]
+host = 'a.example'
+port = 80
+
+[[replicas]]
+host = 'b.example'
+
+[[replicas]]
+host = 'c.example'
+port = 81
+`, buf.String())
+
+	got, err := FromTOML[testEncodedConfig](&buf)
+	require.NoError(t, err)
+	assert.Equal(t, testEncoded, got)
+}
+
+func TestEncode_Options(t *testing.T) {
+	type nested struct {
+		Zeta  string            `json:"zeta" yaml:"zeta" toml:"zeta"`
+		Alpha int               `json:"alpha" yaml:"alpha" toml:"alpha"`
+		Inner map[string]string `json:"inner" yaml:"inner" toml:"inner"`
+	}
+	value := nested{Zeta: "z", Inner: map[string]string{"k": "v"}}
+
+	tests := []struct {
+		name   string
+		encode func(*bytes.Buffer, any, ...Option) error
+		opts   []Option
+		want   string
+	}{
+		{
+			name:   "JSONCompact",
+			encode: func(b *bytes.Buffer, v any, opts ...Option) error { return ToJSON(b, v, opts...) },
+			opts:   []Option{Indent(0)},
+			want:   `{"zeta":"z","alpha":0,"inner":{"k":"v"}}` + "\n",
+		},
+		{
+			name:   "JSONSortedOmitZero",
+			encode: func(b *bytes.Buffer, v any, opts ...Option) error { return ToJSON(b, v, opts...) },
+			opts:   []Option{Indent(0), SortKeys(), OmitZero()},
+			want:   `{"inner":{"k":"v"},"zeta":"z"}` + "\n",
+		},
+		{
+			name:   "YAMLIndent",
+			encode: func(b *bytes.Buffer, v any, opts ...Option) error { return ToYAML(b, v, opts...) },
+			opts:   []Option{Indent(4), SortKeys()},
+			want:   "alpha: 0\ninner:\n    k: v\nzeta: z\n",
+		},
+		{
+			name:   "TOMLIndent",
+			encode: func(b *bytes.Buffer, v any, opts ...Option) error { return ToTOML(b, v, opts...) },
+			opts:   []Option{Indent(2)},
+			want:   "zeta = 'z'\nalpha = 0\n\n[inner]\n  k = 'v'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, tt.encode(&buf, value, tt.opts...))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestToTOML_NotATable(t *testing.T) {
+	err := ToTOML(&bytes.Buffer{}, []int{1})
+	assert.ErrorContains(t, err, "must be tables")
+}
+
+func TestEncode_InvalidIndent(t *testing.T) {
+	err := ToYAML(&bytes.Buffer{}, testEncoded, Indent(-1))
+	assert.EqualError(t, err, "invalid indent of -1 spaces for yaml")
+	err = ToYAML(&bytes.Buffer{}, testEncoded, Indent(1))
+	assert.EqualError(t, err, "invalid indent of 1 spaces for yaml")
+	err = ToJSON(&bytes.Buffer{}, testEncoded, Indent(-2))
+	assert.EqualError(t, err, "invalid indent of -2 spaces for json")
+}
+
+func TestEncode_OmitEmptyKeepsStructs(t *testing.T) {
+	type wrapper struct {
+		Server testEncodedServer `json:"server,omitempty" yaml:"server,omitempty"`
+		Name   string            `json:"name,omitempty" yaml:"name,omitempty"`
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ToJSON(&buf, wrapper{}, Indent(0)))
+	assert.Equal(t, `{"server":{"host":""}}`+"\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, ToYAML(&buf, wrapper{}, OmitZero()))
+	assert.Equal(t, "server: {}\n", buf.String())
+}
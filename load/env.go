@@ -0,0 +1,284 @@
+package load
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FromEnv fills a struct from environment variables. A field tagged with
+// `env:"NAME"` reads the variable NAME; untagged fields read a variable
+// named after the prefix and their field path, e.g. APP_DB_HOST for the
+// Host field of the DB struct with prefix "APP". A tag on a nested struct
+// replaces the prefix of its fields.
+//
+// Slices are read as comma-separated lists and maps as comma-separated
+// k=v pairs. Durations, pointers and encoding.TextUnmarshaler types are
+// supported. Variables are resolved through lookup, or os.LookupEnv when
+// it is nil.
+func FromEnv[T any](prefix string, lookup LookupFunc, opts ...Option) (T, error) {
+	var v T
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	o := newOptions(opts)
+
+	target := reflect.ValueOf(&v).Elem()
+	for target.Kind() == reflect.Pointer {
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.Struct {
+		return v, fmt.Errorf("cannot bind environment to %s: not a struct", target.Type())
+	}
+
+	var errs []error
+	for _, f := range envFields(target.Type(), prefix) {
+		value, ok := lookup(f.name)
+		if !ok {
+			continue
+		}
+		if err := setFromString(allocField(target, f.index), value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		var zero T
+		return zero, errors.Join(errs...)
+	}
+	if o.validate {
+		if err := Validate(v); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+	return v, nil
+}
+
+// envField is a struct field that an environment variable sets.
+type envField struct {
+	name  string
+	index []int
+	field reflect.StructField
+}
+
+// envFields lists the fields of a struct type that environment variables
+// set, along with the names of those variables. FromEnv and EnvSource
+// share it so that both read the same variables for the same type.
+func envFields(t reflect.Type, prefix string) []envField {
+	var fields []envField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("env")
+		if tag == "-" {
+			continue
+		}
+
+		if isNestedStruct(f.Type) {
+			nestedPrefix := tag
+			if nestedPrefix == "" {
+				nestedPrefix = envName(prefix, []string{envSegment(f)})
+				if f.Anonymous {
+					nestedPrefix = prefix
+				}
+			}
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			for _, sub := range envFields(ft, nestedPrefix) {
+				sub.index = append([]int{i}, sub.index...)
+				fields = append(fields, sub)
+			}
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = envName(prefix, []string{envSegment(f)})
+		}
+		fields = append(fields, envField{name: name, index: []int{i}, field: f})
+	}
+	return fields
+}
+
+// allocField returns the field of a struct at the given index path,
+// allocating the nil pointers to nested structs along the way so that
+// they are only set when one of their fields is.
+func allocField(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// isNestedStruct reports whether a field type is a struct whose fields are
+// bound individually rather than parsed from a single value.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// envSegment derives the part of an environment variable name that a
+// struct field contributes, preferring its document key.
+func envSegment(f reflect.StructField) string {
+	for _, tag := range []string{"yaml", "json"} {
+		if name, _ := parseTag(f.Tag.Get(tag)); name != "" && name != "-" {
+			return name
+		}
+	}
+	return snakeCase(f.Name)
+}
+
+// snakeCase splits a Go identifier into underscore-separated words,
+// keeping acronyms together: "HTTPServerPort" becomes "HTTP_Server_Port".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// setFromString parses a textual value into v according to its type.
+func setFromString(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		target := reflect.New(v.Type().Elem())
+		if err := setFromString(target.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(target)
+		return nil
+	}
+
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s))
+			return nil
+		}
+		items := splitList(s)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setFromString(slice.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		pairs, err := splitPairs(s)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range pairs {
+			key, value := pair[0], pair[1]
+			k := reflect.New(v.Type().Key()).Elem()
+			if err := setFromString(k, key); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := setFromString(e, value); err != nil {
+				return fmt.Errorf("value of %q: %w", key, err)
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, trimming whitespace around
+// items and ignoring an empty input.
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	items := strings.Split(s, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
+
+// splitPairs splits a comma-separated list of k=v pairs, trimming
+// whitespace around keys and values.
+func splitPairs(s string) ([][2]string, error) {
+	var pairs [][2]string
+	for _, pair := range splitList(s) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid map entry %q: expected k=v", pair)
+		}
+		pairs = append(pairs, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+	return pairs, nil
+}
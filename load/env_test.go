@@ -0,0 +1,129 @@
+package load
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnvDB struct {
+	Host     string `yaml:"host"`
+	MaxConns int    `yaml:"max_conns"`
+}
+
+type testEnvBinding struct {
+	Name     string
+	Debug    bool
+	Timeout  time.Duration
+	Ratio    float64
+	Tags     []string
+	Ports    []int
+	Labels   map[string]string
+	Limits   map[string]int
+	Address  net.IP
+	Retries  *int
+	Token    string `env:"SECRET_TOKEN"`
+	DB       testEnvDB
+	Replica  *testEnvDB
+	Cache    *testEnvDB
+	Metrics  testEnvDB `env:"METRICS"`
+	Ignored  string    `env:"-"`
+	HTTPPort int
+}
+
+func TestFromEnv(t *testing.T) {
+	lookup := testLookup(map[string]string{
+		"APP_NAME":           "api",
+		"APP_DEBUG":          "true",
+		"APP_TIMEOUT":        "1m30s",
+		"APP_RATIO":          "0.5",
+		"APP_TAGS":           "a, b,c",
+		"APP_PORTS":          "80,443",
+		"APP_LABELS":         "tier=web,team=core",
+		"APP_LIMITS":         "cpu=2, mem=512",
+		"APP_ADDRESS":        "10.0.0.1",
+		"APP_RETRIES":        "3",
+		"SECRET_TOKEN":       "s3cr3t",
+		"APP_DB_HOST":        "db.local",
+		"APP_DB_MAX_CONNS":   "10",
+		"APP_REPLICA_HOST":   "replica.local",
+		"METRICS_HOST":       "metrics.local",
+		"APP_IGNORED":        "nope",
+		"APP_HTTP_PORT":      "8080",
+		"UNRELATED_VARIABLE": "x",
+	})
+
+	got, err := FromEnv[testEnvBinding]("APP", lookup)
+	require.NoError(t, err)
+
+	retries := 3
+	assert.Equal(t, testEnvBinding{
+		Name:     "api",
+		Debug:    true,
+		Timeout:  90 * time.Second,
+		Ratio:    0.5,
+		Tags:     []string{"a", "b", "c"},
+		Ports:    []int{80, 443},
+		Labels:   map[string]string{"tier": "web", "team": "core"},
+		Limits:   map[string]int{"cpu": 2, "mem": 512},
+		Address:  net.ParseIP("10.0.0.1"),
+		Retries:  &retries,
+		Token:    "s3cr3t",
+		DB:       testEnvDB{Host: "db.local", MaxConns: 10},
+		Replica:  &testEnvDB{Host: "replica.local"},
+		Metrics:  testEnvDB{Host: "metrics.local"},
+		HTTPPort: 8080,
+	}, got)
+	assert.Nil(t, got.Cache)
+}
+
+func TestFromEnv_Errors(t *testing.T) {
+	lookup := testLookup(map[string]string{
+		"APP_DEBUG":   "maybe",
+		"APP_TIMEOUT": "soon",
+		"APP_LABELS":  "tier",
+	})
+
+	_, err := FromEnv[testEnvBinding]("APP", lookup)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "APP_DEBUG")
+	assert.Contains(t, err.Error(), "APP_TIMEOUT")
+	assert.Contains(t, err.Error(), "APP_LABELS")
+
+	_, err = FromEnv[string]("APP", lookup)
+	assert.Error(t, err)
+}
+
+func TestFromEnv_Validation(t *testing.T) {
+	type config struct {
+		Port int `validate:"min=1"`
+	}
+
+	_, err := FromEnv[config]("APP", testLookup(map[string]string{"APP_PORT": "0"}), WithValidation())
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestFromEnv_ProcessEnvironment(t *testing.T) {
+	t.Setenv("LOADTEST_NAME", "from-env")
+
+	got, err := FromEnv[testEnvBinding]("LOADTEST", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", got.Name)
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Name":           "Name",
+		"MaxConns":       "Max_Conns",
+		"HTTPServerPort": "HTTP_Server_Port",
+		"DB":             "DB",
+		"Port2":          "Port2",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, snakeCase(input), input)
+	}
+}
@@ -0,0 +1,394 @@
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeError reports a syntax or type error in a document along with
+// where it occurred.
+type DecodeError struct {
+	// Source names the document, e.g. its file path. It is empty for
+	// anonymous readers unless SourceName is provided.
+	Source string
+	// Line and Column locate the error, starting at 1. They are 0 when
+	// the position is unknown.
+	Line   int
+	Column int
+	// Path is the key path of the offending value, e.g. "servers[1].port".
+	Path string
+	// Expected and Actual describe a type mismatch, e.g. "int" and "string".
+	Expected string
+	Actual   string
+	// Err is the underlying decoder error.
+	Err error
+
+	text []byte
+}
+
+func (e *DecodeError) Error() string {
+	var b strings.Builder
+	if location := e.location(); location != "" {
+		b.WriteString(location)
+		b.WriteString(": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.message())
+	return b.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Snippet renders the offending source line with a caret under the error
+// column, for display in command-line output. It is empty when the source
+// text or the line is unknown.
+func (e *DecodeError) Snippet() string {
+	if e.Line <= 0 || e.text == nil {
+		return ""
+	}
+	lines := strings.Split(string(e.text), "\n")
+	if e.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[e.Line-1], "\r")
+	number := strconv.Itoa(e.Line)
+	gutter := strings.Repeat(" ", len(number))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s | %s\n", number, line)
+	if e.Column > 0 {
+		// Keep tabs in the padding so the caret lines up with the source.
+		var pad strings.Builder
+		for i, r := range line {
+			if i >= e.Column-1 {
+				break
+			}
+			if r == '\t' {
+				pad.WriteRune('\t')
+			} else {
+				pad.WriteRune(' ')
+			}
+		}
+		fmt.Fprintf(&b, "%s | %s^\n", gutter, pad.String())
+	}
+	return b.String()
+}
+
+// location renders the source, line and column in the conventional
+// "file:line:column" form.
+func (e *DecodeError) location() string {
+	parts := make([]string, 0, 3)
+	if e.Source != "" {
+		parts = append(parts, e.Source)
+	}
+	if e.Line > 0 {
+		parts = append(parts, strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			parts = append(parts, strconv.Itoa(e.Column))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// message describes the error without its location.
+func (e *DecodeError) message() string {
+	if e.Expected != "" && e.Actual != "" {
+		return fmt.Sprintf("cannot decode %s into %s", e.Actual, e.Expected)
+	}
+	msg := e.Err.Error()
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		return m[2]
+	}
+	return strings.TrimPrefix(msg, "toml: ")
+}
+
+// SourceName names the document being decoded, so that decode errors can
+// point at it. FromFile and FromFS set it to the path of the file.
+func SourceName(name string) Option {
+	return func(o *options) {
+		o.sourceName = name
+	}
+}
+
+// annotateDecodeErrors attaches the source name and text to every
+// DecodeError found in err.
+func annotateDecodeErrors(err error, name string, text []byte) error {
+	if err == nil {
+		return nil
+	}
+	var visit func(err error)
+	visit = func(err error) {
+		if de, ok := err.(*DecodeError); ok {
+			if de.Source == "" {
+				de.Source = name
+			}
+			if de.text == nil {
+				de.text = text
+			}
+			return
+		}
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range wrapped.Unwrap() {
+				visit(e)
+			}
+		case interface{ Unwrap() error }:
+			if inner := wrapped.Unwrap(); inner != nil {
+				visit(inner)
+			}
+		}
+	}
+	visit(err)
+	return err
+}
+
+// toDecodeError converts a decoder error into a *DecodeError where
+// possible. decoded is the text the decoder saw, which differs from the
+// original text when document-level options rewrote it; positions are
+// always reported against the original text.
+func toDecodeError(format Format, original []byte, decoded []byte, err error) error {
+	switch format {
+	case FormatJSON:
+		return jsonDecodeError(original, decoded, err)
+	case FormatYAML:
+		var root yaml.Node
+		if yaml.Unmarshal(original, &root) != nil {
+			return yamlDecodeError(nil, err)
+		}
+		return yamlDecodeError(&root, err)
+	case FormatTOML:
+		return tomlDecodeError(err)
+	}
+	return err
+}
+
+func jsonDecodeError(original []byte, decoded []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		line, column := lineColumn(original, syntaxErr.Offset-1)
+		return &DecodeError{Line: line, Column: column, Err: err}
+	case errors.As(err, &typeErr):
+		de := &DecodeError{Expected: typeErr.Type.String(), Actual: typeErr.Value, Err: err}
+		spans := scanJSON(decoded)
+		if span, ok := spanAt(spans, typeErr.Offset-1); ok {
+			de.Path = span.path
+			if !bytes.Equal(original, decoded) {
+				span, ok = spanByPath(scanJSON(original), span.path)
+			}
+			if ok {
+				de.Line, de.Column = lineColumn(original, span.start)
+			}
+		}
+		return de
+	}
+	return err
+}
+
+var (
+	yamlLinePattern      = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	yamlUnmarshalPattern = regexp.MustCompile("^line (\\d+): cannot unmarshal (!!\\w+)(?: `(.*)`)? into (.+)$")
+)
+
+// yamlTagNames describes YAML core tags in plain words.
+var yamlTagNames = map[string]string{
+	"!!str":       "string",
+	"!!int":       "integer",
+	"!!float":     "float",
+	"!!bool":      "boolean",
+	"!!null":      "null",
+	"!!map":       "mapping",
+	"!!seq":       "sequence",
+	"!!timestamp": "timestamp",
+	"!!binary":    "binary",
+}
+
+// yamlDecodeError converts a yaml.v3 error, locating the offending value
+// in the node tree when one is available.
+func yamlDecodeError(root *yaml.Node, err error) error {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		errs := make([]error, 0, len(typeErr.Errors))
+		for _, msg := range typeErr.Errors {
+			errs = append(errs, yamlTypeError(root, msg))
+		}
+		if len(errs) == 1 {
+			return errs[0]
+		}
+		return errors.Join(errs...)
+	}
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &DecodeError{Line: line, Err: err}
+	}
+	return err
+}
+
+// yamlTypeError converts a single message of a yaml.TypeError.
+func yamlTypeError(root *yaml.Node, msg string) error {
+	m := yamlUnmarshalPattern.FindStringSubmatch(msg)
+	if m == nil {
+		de := &DecodeError{Err: errors.New(msg)}
+		if lm := yamlLinePattern.FindStringSubmatch(msg); lm != nil {
+			de.Line, _ = strconv.Atoi(lm[1])
+			de.Err = errors.New(lm[2])
+		}
+		return de
+	}
+
+	line, _ := strconv.Atoi(m[1])
+	actual, ok := yamlTagNames[m[2]]
+	if !ok {
+		actual = m[2]
+	}
+	de := &DecodeError{Line: line, Expected: m[4], Actual: actual, Err: errors.New(msg)}
+	if root != nil {
+		value := strings.TrimSuffix(m[3], "...")
+		walkNodes(root, "", func(node *yaml.Node, path string) bool {
+			if node.Line == line && node.ShortTag() == m[2] && strings.HasPrefix(node.Value, value) {
+				de.Path, de.Column = path, node.Column
+				return false
+			}
+			return true
+		})
+	}
+	return de
+}
+
+func tomlDecodeError(err error) error {
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		line, column := decodeErr.Position()
+		return &DecodeError{Line: line, Column: column, Path: strings.Join(decodeErr.Key(), "."), Err: err}
+	}
+	return err
+}
+
+// jsonSpan records where a value of a JSON document starts and ends.
+type jsonSpan struct {
+	path  string
+	start int64
+	end   int64
+}
+
+// scanJSON tokenizes a JSON document and records the span of every value.
+func scanJSON(data []byte) []jsonSpan {
+	type frame struct {
+		path   string
+		start  int64
+		object bool
+		key    string
+		hasKey bool
+		index  int
+	}
+
+	var spans []jsonSpan
+	var stack []*frame
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	valuePath := func() string {
+		if len(stack) == 0 {
+			return ""
+		}
+		top := stack[len(stack)-1]
+		if top.object {
+			return joinPath(top.path, top.key)
+		}
+		return indexPath(top.path, top.index)
+	}
+	advance := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		if top.object {
+			top.hasKey = false
+		} else {
+			top.index++
+		}
+	}
+
+	for {
+		before := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return spans
+		}
+		after := dec.InputOffset()
+		start := before
+		for start < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[start]) >= 0 {
+			start++
+		}
+
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if key, ok := tok.(string); ok && top.object && !top.hasKey {
+				top.key, top.hasKey = key, true
+				continue
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			stack = append(stack, &frame{path: valuePath(), start: start, object: tok == json.Delim('{')})
+		case json.Delim('}'), json.Delim(']'):
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			spans = append(spans, jsonSpan{path: top.path, start: top.start, end: after})
+			advance()
+		default:
+			spans = append(spans, jsonSpan{path: valuePath(), start: start, end: after})
+			advance()
+		}
+	}
+}
+
+// spanAt finds the innermost value containing the given offset.
+func spanAt(spans []jsonSpan, offset int64) (jsonSpan, bool) {
+	var best jsonSpan
+	found := false
+	for _, s := range spans {
+		if s.start <= offset && offset < s.end && (!found || s.start >= best.start) {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// spanByPath finds the value at the given document path.
+func spanByPath(spans []jsonSpan, path string) (jsonSpan, bool) {
+	for _, s := range spans {
+		if s.path == path {
+			return s, true
+		}
+	}
+	return jsonSpan{}, false
+}
+
+// lineColumn converts a byte offset into a 1-based line and column.
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
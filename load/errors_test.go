@@ -0,0 +1,124 @@
+package load
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeError_JSONTypeMismatch(t *testing.T) {
+	data := "{\n  \"name\": \"api\",\n  \"servers\": [\n    {\"host\": \"a\", \"port\": 1},\n    {\"host\": \"b\", \"port\": \"eighty\"}\n  ]\n}\n"
+
+	_, err := FromJSON[testCluster](strings.NewReader(data), SourceName("cluster.json"))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+
+	assert.Equal(t, "cluster.json", decodeErr.Source)
+	assert.Equal(t, 5, decodeErr.Line)
+	assert.Equal(t, 27, decodeErr.Column)
+	assert.Equal(t, "servers[1].port", decodeErr.Path)
+	assert.Equal(t, "int", decodeErr.Expected)
+	assert.Equal(t, "string", decodeErr.Actual)
+	assert.Equal(t, "cluster.json:5:27: servers[1].port: cannot decode string into int", err.Error())
+	assert.Equal(t, "5 |     {\"host\": \"b\", \"port\": \"eighty\"}\n  |                           ^\n", decodeErr.Snippet())
+}
+
+func TestDecodeError_JSONSyntax(t *testing.T) {
+	data := "{\n  \"name\": \"api\",\n  \"port\" 80\n}\n"
+
+	_, err := FromJSON[testCluster](strings.NewReader(data))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+
+	assert.Equal(t, 3, decodeErr.Line)
+	assert.Equal(t, 10, decodeErr.Column)
+	assert.Contains(t, decodeErr.Snippet(), "3 |   \"port\" 80")
+}
+
+func TestDecodeError_JSONAfterInterpolation(t *testing.T) {
+	data := "{\n  \"name\": \"${NAME}\",\n  \"servers\": [{\"host\": \"${HOST}\", \"port\": true}]\n}\n"
+	lookup := testLookup(map[string]string{"NAME": "api", "HOST": "db"})
+
+	_, err := FromJSON[testCluster](strings.NewReader(data), ExpandEnv(lookup))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+
+	assert.Equal(t, "servers[0].port", decodeErr.Path)
+	assert.Equal(t, 3, decodeErr.Line)
+	assert.Equal(t, "bool", decodeErr.Actual)
+}
+
+func TestDecodeError_YAMLTypeMismatch(t *testing.T) {
+	data := "name: api\nservers:\n  - host: a\n    port: 1\n  - host: b\n    port: eighty\n"
+
+	for _, opts := range [][]Option{nil, {ExpandEnv(testLookup(nil))}} {
+		_, err := FromYAML[testCluster](strings.NewReader(data), append(opts, SourceName("cluster.yaml"))...)
+		var decodeErr *DecodeError
+		require.ErrorAs(t, err, &decodeErr)
+
+		assert.Equal(t, "cluster.yaml", decodeErr.Source)
+		assert.Equal(t, 6, decodeErr.Line)
+		assert.Equal(t, 11, decodeErr.Column)
+		assert.Equal(t, "servers[1].port", decodeErr.Path)
+		assert.Equal(t, "int", decodeErr.Expected)
+		assert.Equal(t, "string", decodeErr.Actual)
+		assert.Equal(t, "6 |     port: eighty\n  |           ^\n", decodeErr.Snippet())
+	}
+}
+
+func TestDecodeError_YAMLMultipleMismatches(t *testing.T) {
+	data := "name: [a]\nservers:\n  - port: x\n"
+
+	_, err := FromYAML[testCluster](strings.NewReader(data))
+	require.Error(t, err)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok)
+	var paths []string
+	for _, e := range joined.Unwrap() {
+		var decodeErr *DecodeError
+		require.True(t, errors.As(e, &decodeErr))
+		paths = append(paths, decodeErr.Path)
+	}
+	assert.Equal(t, []string{"name", "servers[0].port"}, paths)
+}
+
+func TestDecodeError_YAMLSyntax(t *testing.T) {
+	_, err := FromYAML[testCluster](strings.NewReader("name: api\nservers: [unclosed\n"))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Positive(t, decodeErr.Line)
+	assert.NotContains(t, decodeErr.Error(), "yaml: line")
+}
+
+func TestDecodeError_TOML(t *testing.T) {
+	_, err := FromTOML[testCluster](strings.NewReader("name = \"api\"\nport = = 1\n"), SourceName("cluster.toml"))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "cluster.toml", decodeErr.Source)
+	assert.Equal(t, 2, decodeErr.Line)
+	assert.Positive(t, decodeErr.Column)
+	assert.NotEmpty(t, decodeErr.Snippet())
+}
+
+func TestDecodeError_FromFS(t *testing.T) {
+	rootFS := fstest.MapFS{
+		"configs/cluster.yaml": &fstest.MapFile{Data: []byte("name: api\nservers:\n  - port: eighty\n")},
+	}
+
+	_, err := FromFS[testCluster](rootFS, "configs/cluster.yaml")
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "configs/cluster.yaml:3:11: servers[0].port: cannot decode string into int", err.Error())
+}
+
+func TestDecodeError_SnippetWithoutSource(t *testing.T) {
+	err := &DecodeError{Line: 2, Err: errors.New("boom")}
+	assert.Empty(t, err.Snippet())
+	assert.Equal(t, "2: boom", err.Error())
+	assert.ErrorIs(t, err, err.Err)
+}
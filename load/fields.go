@@ -0,0 +1,126 @@
+package load
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// structField describes how a struct field is addressed in a document.
+type structField struct {
+	key   string
+	index []int
+	field reflect.StructField
+	// inline marks a YAML inline map, which has no key of its own and
+	// collects every key that no other field claims.
+	inline bool
+}
+
+// structFields lists the document keys of a struct type as seen by the
+// encoding that uses the given struct tag. Embedded structs are flattened
+// the same way the corresponding encoder flattens them.
+// An inline map is listed with an empty key and the inline flag set.
+func structFields(t reflect.Type, tag string) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		name, opts := parseTag(f.Tag.Get(tag))
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		inline := hasTagOption(opts, "inline") ||
+			(tag != "yaml" && f.Anonymous && name == "" && ft.Kind() == reflect.Struct)
+		if inline && ft.Kind() == reflect.Struct {
+			for _, sub := range structFields(ft, tag) {
+				sub.index = append([]int{i}, sub.index...)
+				fields = append(fields, sub)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if inline && tag == "yaml" && ft.Kind() == reflect.Map && ft.Key().Kind() == reflect.String {
+			fields = append(fields, structField{index: []int{i}, field: f, inline: true})
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+			if tag == "yaml" {
+				name = strings.ToLower(name)
+			}
+		}
+		fields = append(fields, structField{key: name, index: []int{i}, field: f})
+	}
+	return fields
+}
+
+// lookupField finds the struct field addressed by a document key. YAML
+// matches keys exactly while JSON and TOML fall back to case-insensitive
+// matching, mirroring their respective decoders.
+func lookupField(fields []structField, key string, tag string) (structField, bool) {
+	for _, f := range fields {
+		if !f.inline && f.key == key {
+			return f, true
+		}
+	}
+	if tag == "yaml" {
+		return structField{}, false
+	}
+	for _, f := range fields {
+		if !f.inline && strings.EqualFold(f.key, key) {
+			return f, true
+		}
+	}
+	return structField{}, false
+}
+
+// inlineField returns the inline map of a struct, if it has one.
+func inlineField(fields []structField) (structField, bool) {
+	for _, f := range fields {
+		if f.inline {
+			return f, true
+		}
+	}
+	return structField{}, false
+}
+
+// parseTag splits a struct tag value into its name and its options.
+func parseTag(value string) (string, string) {
+	name, opts, _ := strings.Cut(value, ",")
+	return name, opts
+}
+
+// hasTagOption reports whether a comma-separated option list contains the
+// given option.
+func hasTagOption(opts string, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath appends a key to a dotted document path.
+func joinPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// indexPath appends a sequence index to a dotted document path.
+func indexPath(parent string, i int) string {
+	return fmt.Sprintf("%s[%d]", parent, i)
+}
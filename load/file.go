@@ -0,0 +1,155 @@
+package load
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format identifies a document encoding supported by the loaders.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// ErrUnsupportedFormat is returned when the format of a document can
+// neither be derived from its file extension nor sniffed from its content.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+var (
+	tomlTablePattern    = regexp.MustCompile(`^\[\[?\s*[A-Za-z0-9_\-."' ]+\s*\]\]?\s*(#.*)?$`)
+	tomlKeyValuePattern = regexp.MustCompile(`^[A-Za-z0-9_\-."']+\s*=`)
+)
+
+// FormatFromPath derives the document format from the extension of the
+// given path. Paths without an extension yield an empty format and no
+// error, signalling that the content has to be sniffed instead. The
+// extension of a compressed file is skipped, so "config.json.gz" is JSON.
+func FormatFromPath(name string) (Format, error) {
+	ext := strings.ToLower(path.Ext(trimCompressionExt(name)))
+	switch ext {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	case "":
+		return "", nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, ext)
+	}
+}
+
+// DetectFormat inspects the content of a document and guesses its format.
+func DetectFormat(data []byte) (Format, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return "", fmt.Errorf("%w: empty document", ErrUnsupportedFormat)
+	}
+	if json.Valid(trimmed) {
+		return FormatJSON, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "---" || strings.HasPrefix(line, "%YAML") {
+			return FormatYAML, nil
+		}
+		if tomlTablePattern.MatchString(line) || tomlKeyValuePattern.MatchString(line) {
+			return FormatTOML, nil
+		}
+		break
+	}
+
+	var generic any
+	if err := yaml.Unmarshal(trimmed, &generic); err == nil {
+		switch generic.(type) {
+		case map[string]any, map[any]any, []any:
+			return FormatYAML, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unable to detect format from content", ErrUnsupportedFormat)
+}
+
+// FromFile loads and parses the file at the given path into any arbitrary
+// Go type, picking the decoder from the file extension. Files without an
+// extension have their format detected from their content. Files
+// compressed with gzip or bzip2 are decompressed first.
+func FromFile[T any](name string, opts ...Option) (T, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		var v T
+		return v, err
+	}
+	return fromFile[T](name, f, opts)
+}
+
+// FromFS loads and parses the file at the given path inside the provided
+// filesystem, such as the root directory injected into a command context
+// by fileutils.ApplyRootDirToContext.
+func FromFS[T any](fsys fs.FS, name string, opts ...Option) (T, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		var v T
+		return v, err
+	}
+	return fromFile[T](name, f, opts)
+}
+
+// fromFile reads an opened file, honouring MaxBytes, and decodes it.
+func fromFile[T any](name string, f io.ReadCloser, opts []Option) (T, error) {
+	defer f.Close()
+	data, err := readAll(f, newOptions(opts))
+	if err != nil {
+		var v T
+		return v, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	return fromBytes[T](name, data, opts)
+}
+
+// fromBytes decompresses a named document, resolves its format and
+// decodes it.
+func fromBytes[T any](name string, data []byte, opts []Option) (T, error) {
+	var v T
+	data, err := decompressBytes(data, newOptions(opts))
+	if err != nil {
+		return v, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	format, err := FormatFromPath(name)
+	if err != nil {
+		return v, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	if format == "" {
+		if format, err = DetectFormat(data); err != nil {
+			return v, fmt.Errorf("failed to load %s: %w", name, err)
+		}
+	}
+	v, err = decode[T](format, bytes.NewReader(data), append([]Option{SourceName(name)}, opts...))
+	if err != nil {
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			return v, err
+		}
+		return v, fmt.Errorf("failed to decode %s as %s: %w", name, format, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,140 @@
+package load
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jgfranco17/dev-tooling-go/fileutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    Format
+		wantErr bool
+	}{
+		{name: "json", path: "config.json", want: FormatJSON},
+		{name: "yaml", path: "config.yaml", want: FormatYAML},
+		{name: "yml", path: "dir/config.yml", want: FormatYAML},
+		{name: "toml", path: "config.toml", want: FormatTOML},
+		{name: "uppercase extension", path: "CONFIG.JSON", want: FormatJSON},
+		{name: "no extension", path: "config", want: ""},
+		{name: "gzip", path: "config.json.gz", want: FormatJSON},
+		{name: "bzip2", path: "config.yaml.BZ2", want: FormatYAML},
+		{name: "compressed without extension", path: "config.gz", want: ""},
+		{name: "unsupported extension", path: "config.ini", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatFromPath(tt.path)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnsupportedFormat)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Format
+		wantErr bool
+	}{
+		{name: "json object", data: `{"name":"Ada"}`, want: FormatJSON},
+		{name: "json array", data: "  [1, 2, 3]\n", want: FormatJSON},
+		{name: "yaml mapping", data: "name: Ada\nage: 42\n", want: FormatYAML},
+		{name: "yaml document marker", data: "# comment\n---\nname: Ada\n", want: FormatYAML},
+		{name: "yaml sequence", data: "- a\n- b\n", want: FormatYAML},
+		{name: "toml key value", data: "# comment\nname = \"Ada\"\n", want: FormatTOML},
+		{name: "toml table", data: "[owner]\nname = \"Ada\"\n", want: FormatTOML},
+		{name: "toml array of tables", data: "[[backends]]\nhost = \"a\"\n", want: FormatTOML},
+		{name: "empty", data: "  \n", wantErr: true},
+		{name: "plain text", data: "just some words", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat([]byte(tt.data))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnsupportedFormat)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFromFS(t *testing.T) {
+	rootFS := fstest.MapFS{
+		"person.json":       &fstest.MapFile{Data: []byte(`{"name":"Ada","age":42}`)},
+		"person.yaml":       &fstest.MapFile{Data: []byte("name: Ada\nage: 42\n")},
+		"nested/person.yml": &fstest.MapFile{Data: []byte("name: Ada\nage: 42\n")},
+		"person.toml":       &fstest.MapFile{Data: []byte("name = \"Ada\"\nage = 42\n")},
+		"person":            &fstest.MapFile{Data: []byte("name: Ada\nage: 42\n")},
+	}
+
+	for _, name := range []string{"person.json", "person.yaml", "nested/person.yml", "person.toml", "person"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := FromFS[testPerson](rootFS, name)
+			require.NoError(t, err)
+			assert.Equal(t, testPerson{Name: "Ada", Age: 42}, got)
+		})
+	}
+}
+
+func TestFromFS_Errors(t *testing.T) {
+	rootFS := fstest.MapFS{
+		"person.ini":  &fstest.MapFile{Data: []byte("name=Ada")},
+		"notes":       &fstest.MapFile{Data: []byte("just some words")},
+		"broken.json": &fstest.MapFile{Data: []byte(`{"name":`)},
+	}
+
+	_, err := FromFS[testPerson](rootFS, "person.ini")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = FromFS[testPerson](rootFS, "notes")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = FromFS[testPerson](rootFS, "broken.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken.json")
+
+	_, err = FromFS[testPerson](rootFS, "missing.json")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFromFS_RootDirFromContext(t *testing.T) {
+	rootFS := fstest.MapFS{
+		"configs/person.yaml": &fstest.MapFile{Data: []byte("name: Ada\nage: 42\n")},
+	}
+	ctx := fileutils.ApplyRootDirToContext(context.Background(), rootFS)
+
+	got, err := FromFS[testPerson](fileutils.RootDirFromContext(ctx), "configs/person.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, testPerson{Name: "Ada", Age: 42}, got)
+}
+
+func TestFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "person.toml")
+	require.NoError(t, os.WriteFile(path, []byte("name = \"Ada\"\nage = 42\n"), 0o600))
+
+	got, err := FromFile[testPerson](path)
+	require.NoError(t, err)
+	assert.Equal(t, testPerson{Name: "Ada", Age: 42}, got)
+
+	_, err = FromFile[testPerson](filepath.Join(dir, "missing.toml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
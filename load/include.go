@@ -0,0 +1,175 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeTag and includeKey mark the places where documents include other
+// files.
+const (
+	includeTag = "!include"
+	includeKey = "$include"
+)
+
+// ErrIncludeCycle is returned when a file includes itself, directly or
+// through other files.
+var ErrIncludeCycle = errors.New("include cycle")
+
+// IncludeError reports a file that could not be included, along with the
+// chain of includes that led to it.
+type IncludeError struct {
+	// Chain lists the files from the including document down to the file
+	// that failed, e.g. ["config.yaml", "servers.yaml", "db.yaml"]. The
+	// including document is missing when it has no SourceName.
+	Chain []string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("include %s: %v", strings.Join(e.Chain, " -> "), e.Err)
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// Includes resolves include directives against the files of fsys before a
+// document is decoded. A YAML value tagged `!include path.yaml` and an
+// object consisting only of `{"$include": "path.json"}` are both replaced
+// by the contents of the named file, which may be in any supported format
+// and may include further files.
+//
+// Paths are relative to the including file. The document itself is
+// located through SourceName, which FromFS sets to its path; documents
+// read from other sources resolve paths against the root of fsys.
+func Includes(fsys fs.FS) Option {
+	return func(o *options) {
+		o.includeFS = fsys
+	}
+}
+
+// resolveIncludes replaces the include directives of a node tree with the
+// files they name. Included files are subject to the same limits as the
+// including document.
+func resolveIncludes(node *yaml.Node, o *options) error {
+	var chain []string
+	dir := "."
+	if source := o.sourceName; source != "" {
+		chain = []string{source}
+		if fs.ValidPath(source) {
+			dir = path.Dir(source)
+		}
+	}
+	return includeNode(node, o, dir, chain)
+}
+
+// includeNode resolves the includes of a node. dir is the directory of the
+// file the node was read from, and chain the files included so far.
+func includeNode(node *yaml.Node, o *options, dir string, chain []string) error {
+	target, ok, err := includeTarget(node)
+	if err != nil {
+		return &IncludeError{Chain: chain, Err: err}
+	}
+	if ok {
+		return includeFile(node, o, path.Join(dir, target), chain)
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := includeNode(child, o, dir, chain); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := includeNode(node.Content[i], o, dir, chain); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// includeTarget returns the path named by an include directive, and
+// whether the node is one.
+func includeTarget(node *yaml.Node) (string, bool, error) {
+	switch {
+	case node.Tag == includeTag:
+		if node.Kind != yaml.ScalarNode || node.Value == "" {
+			return "", false, fmt.Errorf("line %d: %s expects a file path", node.Line, includeTag)
+		}
+		return node.Value, true, nil
+	case node.Kind == yaml.MappingNode && len(node.Content) == 2 && node.Content[0].Value == includeKey:
+		value := node.Content[1]
+		if value.Kind != yaml.ScalarNode || value.ShortTag() != "!!str" || value.Value == "" {
+			return "", false, fmt.Errorf("%s expects a file path", includeKey)
+		}
+		return value.Value, true, nil
+	}
+	return "", false, nil
+}
+
+// includeFile replaces node with the contents of the named file.
+func includeFile(node *yaml.Node, o *options, name string, chain []string) error {
+	chain = append(slices.Clip(chain), name)
+	if slices.Contains(chain[:len(chain)-1], name) {
+		return &IncludeError{Chain: chain, Err: ErrIncludeCycle}
+	}
+
+	data, err := readFile(o.includeFS, name, o)
+	if err == nil {
+		data, err = decompressBytes(data, o)
+	}
+	if err != nil {
+		return &IncludeError{Chain: chain, Err: err}
+	}
+	format, err := FormatFromPath(name)
+	if err == nil && format == "" {
+		format, err = DetectFormat(data)
+	}
+	if err != nil {
+		return &IncludeError{Chain: chain, Err: err}
+	}
+	c, err := codecFor(format)
+	if err != nil {
+		return &IncludeError{Chain: chain, Err: err}
+	}
+	included, err := c.parse(data, o)
+	if errors.Is(err, io.EOF) {
+		included, err = nodeFromValue(nil)
+	}
+	if err != nil {
+		err = annotateDecodeErrors(toDecodeError(format, data, data, err), name, data)
+		return &IncludeError{Chain: chain, Err: err}
+	}
+	if err := checkLimits(included, o); err != nil {
+		return &IncludeError{Chain: chain, Err: err}
+	}
+
+	content := documentContent(included)
+	if err := includeNode(content, o, path.Dir(name), chain); err != nil {
+		return err
+	}
+	*node = *content
+	return nil
+}
+
+// readFile reads a file of fsys, honouring MaxBytes.
+func readFile(fsys fs.FS, name string, o *options) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAll(f, o)
+}
@@ -0,0 +1,127 @@
+package load
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml": {Data: []byte(`name: api
+servers: !include servers/all.yaml
+owner: !include owner.json
+`)},
+		"servers/all.yaml": {Data: []byte(`- !include primary.json
+- host: b.example
+  port: 81
+`)},
+		"servers/primary.json": {Data: []byte(`{"host": "a.example", "port": 80}`)},
+		"owner.json":           {Data: []byte(`{"$include": "people/owner.toml"}`)},
+		"people/owner.toml":    {Data: []byte("host = \"owner.example\"\nport = 22\n")},
+	}
+
+	got, err := FromFS[testValidatedConfig](fsys, "config.yaml", Includes(fsys))
+	require.NoError(t, err)
+	assert.Equal(t, "api", got.Name)
+	assert.Equal(t, []testValidatedServer{
+		{Host: "a.example", Port: 80},
+		{Host: "b.example", Port: 81},
+	}, got.Servers)
+	require.NotNil(t, got.Owner)
+	assert.Equal(t, "owner.example", got.Owner.Host)
+}
+
+func TestIncludes_JSON(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/app.json":    {Data: []byte(`{"name": "api", "servers": [{"$include": "server.json"}]}`)},
+		"conf/server.json": {Data: []byte(`{"host": "a.example", "port": 80}`)},
+	}
+
+	got, err := FromFS[testValidatedConfig](fsys, "conf/app.json", Includes(fsys))
+	require.NoError(t, err)
+	assert.Equal(t, []testValidatedServer{{Host: "a.example", Port: 80}}, got.Servers)
+}
+
+func TestIncludes_Reader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"servers.yaml": {Data: []byte("- host: a.example\n  port: 80\n")},
+	}
+	input := "name: api\nservers: !include servers.yaml\nmode: ${MODE}\n"
+
+	got, err := FromYAML[testValidatedConfig](strings.NewReader(input), Includes(fsys), ExpandEnv(testLookup(map[string]string{"MODE": "fast"})))
+	require.NoError(t, err)
+	assert.Equal(t, "fast", got.Mode)
+	assert.Len(t, got.Servers, 1)
+}
+
+func TestIncludes_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     fstest.MapFS
+		wantChain []string
+		wantErr   string
+	}{
+		{
+			name: "Missing",
+			files: fstest.MapFS{
+				"config.yaml":  {Data: []byte("servers: !include servers.yaml\n")},
+				"servers.yaml": {Data: []byte("- !include missing.yaml\n")},
+			},
+			wantChain: []string{"config.yaml", "servers.yaml", "missing.yaml"},
+			wantErr:   "file does not exist",
+		},
+		{
+			name: "Cycle",
+			files: fstest.MapFS{
+				"config.yaml":  {Data: []byte("owner: !include a/owner.yaml\n")},
+				"a/owner.yaml": {Data: []byte("host: !include ../config.yaml\n")},
+			},
+			wantChain: []string{"config.yaml", "a/owner.yaml", "config.yaml"},
+			wantErr:   "include cycle",
+		},
+		{
+			name: "Malformed",
+			files: fstest.MapFS{
+				"config.json": {Data: []byte(`{"owner": {"$include": "owner.yaml"}}`)},
+				"owner.yaml":  {Data: []byte("host: [\n")},
+			},
+			wantChain: []string{"config.json", "owner.yaml"},
+			wantErr:   "owner.yaml:1: did not find expected node content",
+		},
+		{
+			name: "NotAPath",
+			files: fstest.MapFS{
+				"config.yaml": {Data: []byte("owner: !include [a, b]\n")},
+			},
+			wantChain: []string{"config.yaml"},
+			wantErr:   "!include expects a file path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var name string
+			for n := range tt.files {
+				if strings.HasPrefix(n, "config.") {
+					name = n
+				}
+			}
+			_, err := FromFS[testValidatedConfig](tt.files, name, Includes(tt.files))
+			var includeErr *IncludeError
+			require.ErrorAs(t, err, &includeErr)
+			assert.Equal(t, tt.wantChain, includeErr.Chain)
+			assert.Contains(t, err.Error(), strings.Join(tt.wantChain, " -> "))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestIncludes_CycleIsSentinel(t *testing.T) {
+	fsys := fstest.MapFS{"self.yaml": {Data: []byte("name: !include self.yaml\n")}}
+	_, err := FromFS[testValidatedConfig](fsys, "self.yaml", Includes(fsys))
+	assert.ErrorIs(t, err, ErrIncludeCycle)
+}
@@ -0,0 +1,151 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUndefinedVariable is returned when a required variable referenced
+// through ${VAR:?message} is not set.
+var ErrUndefinedVariable = errors.New("undefined variable")
+
+// LookupFunc looks up the value of a variable, reporting whether it is set.
+// os.LookupEnv satisfies it.
+type LookupFunc func(name string) (string, bool)
+
+// ExpandEnv expands variable references inside the string values of a
+// document before it is decoded. Keys are left untouched. The supported
+// forms are:
+//
+//   - ${VAR}: the value of VAR, or an empty string when unset
+//   - ${VAR:-default}: the value of VAR, or default when unset or empty
+//   - ${VAR:?message}: the value of VAR, or an error when unset or empty
+//   - $$: a literal dollar sign
+//
+// Variables are resolved through lookup, or os.LookupEnv when it is nil.
+// Unquoted YAML values are re-typed after expansion, so `port: ${PORT}`
+// decodes into an integer field.
+func ExpandEnv(lookup LookupFunc) Option {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return func(o *options) {
+		o.lookupEnv = lookup
+	}
+}
+
+// expandEnv expands variable references in every value of a node tree.
+func expandEnv(node *yaml.Node, lookup LookupFunc) error {
+	return walkValues(node, "", func(scalar *yaml.Node, path string) error {
+		if scalar.ShortTag() != "!!str" || !strings.Contains(scalar.Value, "$") {
+			return nil
+		}
+		expanded, err := interpolate(scalar.Value, lookup, false)
+		if err != nil {
+			if path == "" {
+				return err
+			}
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		scalar.Value = expanded
+		if scalar.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			scalar.Tag = ""
+		}
+		return nil
+	})
+}
+
+// walkValues calls fn for every scalar value of a node tree along with its
+// document path. Mapping keys and aliases are not visited.
+func walkValues(node *yaml.Node, path string, fn func(scalar *yaml.Node, path string) error) error {
+	var err error
+	walkNodes(node, path, func(n *yaml.Node, p string) bool {
+		if n.Kind == yaml.ScalarNode {
+			err = fn(n, p)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// interpolate expands the variable references of a single string, along
+// with unbraced $VAR references when bare is set.
+func interpolate(s string, lookup LookupFunc, bare bool) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			s = s[i+2:]
+			continue
+		case '{':
+		default:
+			if n := varNameLen(s[i+1:]); bare && n > 0 {
+				value, _ := lookup(s[i+1 : i+1+n])
+				b.WriteString(value)
+				s = s[i+1+n:]
+				continue
+			}
+			b.WriteByte('$')
+			s = s[i+1:]
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s[i:])
+		}
+		value, err := resolveReference(s[i+2:i+end], lookup)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		s = s[i+end+1:]
+	}
+}
+
+// varNameLen returns the length of the variable name at the start of s.
+func varNameLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || i > 0 && '0' <= c && c <= '9' {
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
+
+// resolveReference resolves the body of a ${...} reference.
+func resolveReference(ref string, lookup LookupFunc) (string, error) {
+	if name, fallback, ok := strings.Cut(ref, ":-"); ok {
+		if value, set := lookup(name); set && value != "" {
+			return value, nil
+		}
+		return fallback, nil
+	}
+	if name, message, ok := strings.Cut(ref, ":?"); ok {
+		if value, set := lookup(name); set && value != "" {
+			return value, nil
+		}
+		if message == "" {
+			return "", fmt.Errorf("%w %s", ErrUndefinedVariable, name)
+		}
+		return "", fmt.Errorf("%w %s: %s", ErrUndefinedVariable, name, message)
+	}
+	if ref == "" {
+		return "", fmt.Errorf("empty variable reference")
+	}
+	value, _ := lookup(ref)
+	return value, nil
+}
@@ -0,0 +1,133 @@
+package load
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLookup(vars map[string]string) LookupFunc {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := testLookup(map[string]string{"HOST": "db.local", "EMPTY": ""})
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "plain text", input: "no references", want: "no references"},
+		{name: "simple reference", input: "${HOST}", want: "db.local"},
+		{name: "embedded reference", input: "postgres://${HOST}:5432", want: "postgres://db.local:5432"},
+		{name: "unset reference", input: "[${MISSING}]", want: "[]"},
+		{name: "default when unset", input: "${MISSING:-fallback}", want: "fallback"},
+		{name: "default when empty", input: "${EMPTY:-fallback}", want: "fallback"},
+		{name: "default ignored when set", input: "${HOST:-fallback}", want: "db.local"},
+		{name: "required when set", input: "${HOST:?host is required}", want: "db.local"},
+		{name: "required when unset", input: "${MISSING:?must be set}", wantErr: "undefined variable MISSING: must be set"},
+		{name: "required when empty", input: "${EMPTY:?}", wantErr: "undefined variable EMPTY"},
+		{name: "escaped dollar", input: "cost $$5 ${HOST}", want: "cost $5 db.local"},
+		{name: "bare dollar", input: "$HOST and $", want: "$HOST and $"},
+		{name: "unterminated", input: "${HOST", wantErr: "unterminated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interpolate(tt.input, lookup, false)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type testEnvConfig struct {
+	Host    string            `json:"host" yaml:"host" toml:"host"`
+	Port    int               `json:"port" yaml:"port" toml:"port"`
+	Debug   bool              `json:"debug" yaml:"debug" toml:"debug"`
+	Labels  map[string]string `json:"labels" yaml:"labels" toml:"labels"`
+	Servers []testServer      `json:"servers" yaml:"servers" toml:"servers"`
+}
+
+func TestExpandEnv_YAML(t *testing.T) {
+	lookup := testLookup(map[string]string{"HOST": "db.local", "PORT": "5432", "DEBUG": "true", "KEY": "team"})
+	data := "host: ${HOST}\nport: ${PORT}\ndebug: ${DEBUG}\nlabels:\n  ${KEY}: \"${KEY}\"\nservers:\n  - host: ${HOST:-x}\n"
+
+	got, err := FromYAML[testEnvConfig](strings.NewReader(data), ExpandEnv(lookup))
+	require.NoError(t, err)
+	assert.Equal(t, "db.local", got.Host)
+	assert.Equal(t, 5432, got.Port)
+	assert.True(t, got.Debug)
+	assert.Equal(t, map[string]string{"${KEY}": "team"}, got.Labels)
+	assert.Equal(t, "db.local", got.Servers[0].Host)
+}
+
+func TestExpandEnv_QuotedYAMLStaysString(t *testing.T) {
+	type config struct {
+		Version string `yaml:"version"`
+	}
+	lookup := testLookup(map[string]string{"VERSION": "1.10"})
+
+	got, err := FromYAML[config](strings.NewReader("version: \"${VERSION}\"\n"), ExpandEnv(lookup))
+	require.NoError(t, err)
+	assert.Equal(t, "1.10", got.Version)
+}
+
+func TestExpandEnv_JSONAndTOML(t *testing.T) {
+	lookup := testLookup(map[string]string{"HOST": "db.local"})
+
+	got, err := FromJSON[testEnvConfig](strings.NewReader(`{"host":"${HOST}","port":5432,"servers":[{"host":"${HOST}","port":1}]}`), ExpandEnv(lookup))
+	require.NoError(t, err)
+	assert.Equal(t, "db.local", got.Host)
+	assert.Equal(t, 5432, got.Port)
+	assert.Equal(t, "db.local", got.Servers[0].Host)
+
+	got, err = FromTOML[testEnvConfig](strings.NewReader("host = \"${HOST}\"\nport = 5432\n"), ExpandEnv(lookup))
+	require.NoError(t, err)
+	assert.Equal(t, "db.local", got.Host)
+	assert.Equal(t, 5432, got.Port)
+}
+
+func TestExpandEnv_RequiredVariableNamesPath(t *testing.T) {
+	data := "servers:\n  - host: a\n  - host: ${DB_HOST:?database host is required}\n"
+
+	_, err := FromYAML[testEnvConfig](strings.NewReader(data), ExpandEnv(testLookup(nil)))
+	require.ErrorIs(t, err, ErrUndefinedVariable)
+	assert.Contains(t, err.Error(), "servers[1].host")
+	assert.Contains(t, err.Error(), "database host is required")
+}
+
+func TestExpandEnv_DisabledByDefault(t *testing.T) {
+	got, err := FromYAML[testEnvConfig](strings.NewReader("host: ${HOST}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "${HOST}", got.Host)
+}
+
+func TestExpandEnv_DefaultsToProcessEnvironment(t *testing.T) {
+	t.Setenv("LOAD_TEST_HOST", "from-env")
+
+	got, err := FromYAML[testEnvConfig](strings.NewReader("host: ${LOAD_TEST_HOST}\n"), ExpandEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", got.Host)
+}
+
+func TestExpandEnv_YAMLDocuments(t *testing.T) {
+	lookup := testLookup(map[string]string{"NAME": "Ada"})
+
+	for person, err := range YAMLDocuments[testPerson](strings.NewReader("name: ${NAME}\n"), ExpandEnv(lookup)) {
+		require.NoError(t, err)
+		assert.Equal(t, "Ada", person.Name)
+	}
+}
@@ -0,0 +1,91 @@
+package load
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// LineError reports a malformed record in a line-delimited stream.
+type LineError struct {
+	// Line is the 1-based line number of the record.
+	Line int
+	// Err is the underlying decoding error.
+	Err error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// SkipMalformed makes line-delimited loaders skip malformed records
+// instead of stopping at the first one. Every skipped record is appended
+// to errs when it is not nil.
+func SkipMalformed(errs *[]*LineError) Option {
+	return func(o *options) {
+		o.skipMalformed = true
+		o.malformed = errs
+	}
+}
+
+// FromJSONLines lazily decodes a JSON Lines (NDJSON) stream into T, one
+// record per line. Blank lines are skipped and only a single line is held
+// in memory at a time, so arbitrarily large streams can be processed.
+// Malformed records are yielded as a *LineError, after which the iteration
+// ends unless SkipMalformed is provided.
+func FromJSONLines[T any](data io.Reader, opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		o := newOptions(opts)
+		c := codecs[FormatJSON]
+		reader := bufio.NewReader(newLimitedReader(data, o.maxBytes))
+		for lineNo := 1; ; lineNo++ {
+			line, readErr := reader.ReadBytes('\n')
+			if readErr != nil && !errors.Is(readErr, io.EOF) {
+				var zero T
+				yield(zero, &LineError{Line: lineNo, Err: readErr})
+				return
+			}
+
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				var v T
+				err := decodeLine(c, line, &v, o)
+				switch {
+				case err == nil:
+					if !yield(v, nil) {
+						return
+					}
+				case o.skipMalformed:
+					if o.malformed != nil {
+						*o.malformed = append(*o.malformed, &LineError{Line: lineNo, Err: err})
+					}
+				default:
+					var zero T
+					yield(zero, &LineError{Line: lineNo, Err: err})
+					return
+				}
+			}
+
+			if readErr != nil {
+				return
+			}
+		}
+	}
+}
+
+// decodeLine decodes a single record, rejecting lines that hold anything
+// other than exactly one JSON value.
+func decodeLine(c codec, line []byte, v any, o *options) error {
+	if err := json.Unmarshal(line, new(json.RawMessage)); err != nil {
+		return err
+	}
+	return decodeInto(c, line, v, o)
+}
@@ -0,0 +1,109 @@
+package load
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromJSONLines(t *testing.T) {
+	data := "{\"name\":\"Ada\",\"age\":42}\n\n   \n{\"name\":\"Bob\",\"age\":7}\r\n{\"name\":\"Eve\"}"
+
+	var got []testPerson
+	for person, err := range FromJSONLines[testPerson](strings.NewReader(data)) {
+		require.NoError(t, err)
+		got = append(got, person)
+	}
+
+	assert.Equal(t, []testPerson{
+		{Name: "Ada", Age: 42},
+		{Name: "Bob", Age: 7},
+		{Name: "Eve"},
+	}, got)
+}
+
+func TestFromJSONLines_StopsAtMalformedLine(t *testing.T) {
+	data := "{\"name\":\"Ada\"}\n{\"name\":\n{\"name\":\"Eve\"}\n"
+
+	var names []string
+	var lastErr error
+	for person, err := range FromJSONLines[testPerson](strings.NewReader(data)) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		names = append(names, person.Name)
+	}
+
+	assert.Equal(t, []string{"Ada"}, names)
+	var lineErr *LineError
+	require.ErrorAs(t, lastErr, &lineErr)
+	assert.Equal(t, 2, lineErr.Line)
+	assert.Contains(t, lastErr.Error(), "line 2")
+}
+
+func TestFromJSONLines_SkipMalformed(t *testing.T) {
+	data := "{\"name\":\"Ada\"}\nnot json\n{\"name\":\"Bob\"} {\"name\":\"Eve\"}\n{\"name\":\"Eve\",\"age\":\"old\"}\n{\"name\":\"Zed\"}\n"
+
+	var malformed []*LineError
+	var names []string
+	for person, err := range FromJSONLines[testPerson](strings.NewReader(data), SkipMalformed(&malformed)) {
+		require.NoError(t, err)
+		names = append(names, person.Name)
+	}
+
+	assert.Equal(t, []string{"Ada", "Zed"}, names)
+	require.Len(t, malformed, 3)
+	assert.Equal(t, 2, malformed[0].Line)
+	assert.Equal(t, 3, malformed[1].Line)
+	assert.Equal(t, 4, malformed[2].Line)
+
+	var typeErr *json.UnmarshalTypeError
+	assert.ErrorAs(t, malformed[2], &typeErr)
+}
+
+func TestFromJSONLines_Strict(t *testing.T) {
+	data := "{\"name\":\"Ada\",\"nmae\":\"typo\"}\n"
+
+	for _, err := range FromJSONLines[testPerson](strings.NewReader(data), Strict()) {
+		var unknown *UnknownFieldsError
+		require.ErrorAs(t, err, &unknown)
+		assert.Equal(t, []string{"nmae"}, unknown.Paths)
+	}
+}
+
+// recordStream generates an endless-looking stream of records without
+// holding them in memory.
+type recordStream struct {
+	remaining int
+	pending   []byte
+}
+
+func (s *recordStream) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		if s.remaining == 0 {
+			return 0, io.EOF
+		}
+		s.remaining--
+		s.pending = []byte("{\"name\":\"Ada\",\"age\":42}\n")
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func TestFromJSONLines_Streaming(t *testing.T) {
+	stream := &recordStream{remaining: 100000}
+
+	count := 0
+	for person, err := range FromJSONLines[testPerson](stream) {
+		require.NoError(t, err)
+		require.Equal(t, "Ada", person.Name)
+		count++
+	}
+	assert.Equal(t, 100000, count)
+}
@@ -0,0 +1,174 @@
+package load
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// schemaURL is the location under which a schema passed to WithSchema is
+// registered. Relative references inside it resolve against this URL.
+const schemaURL = "mem:///schema.json"
+
+// SchemaViolation describes a single place where a document breaks its
+// JSON Schema.
+type SchemaViolation struct {
+	// Pointer is the JSON pointer of the offending value, e.g.
+	// "/servers/2/port". It is empty for the document root.
+	Pointer string
+	// Keyword is the JSON pointer of the failed keyword within the schema,
+	// e.g. "/properties/servers/items/properties/port/maximum".
+	Keyword string
+	// Message describes the failure in human-readable form.
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "(root)"
+	}
+	return fmt.Sprintf("%s: %s", pointer, v.Message)
+}
+
+// SchemaError aggregates every violation of a JSON Schema found in a
+// document.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	lines := make([]string, 0, len(e.Violations)+1)
+	lines = append(lines, "schema validation failed:")
+	for _, v := range e.Violations {
+		lines = append(lines, "  "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WithSchema checks documents against a JSON Schema before they are
+// decoded into the target type. The schema is a JSON document using
+// draft 2020-12 unless it declares another dialect in "$schema"; formats
+// such as "email" or "date-time" are asserted. Schemas generated by
+// Schema can be used as is.
+//
+// A document that breaks the schema fails with a *SchemaError listing
+// every violation. YAML and TOML documents are checked in their JSON
+// form, with timestamps kept as strings.
+func WithSchema(schema []byte) Option {
+	compiled, err := compileSchema(schema)
+	return func(o *options) {
+		o.schema, o.schemaErr = compiled, err
+	}
+}
+
+// compileSchema compiles a JSON Schema document.
+func compileSchema(schema []byte) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	c.AssertFormat()
+	if err := c.AddResource(schemaURL, doc); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	compiled, err := c.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return compiled, nil
+}
+
+// checksSchema reports whether documents are validated against a schema.
+func (o *options) checksSchema() bool {
+	return o.schema != nil || o.schemaErr != nil
+}
+
+// checkSchema validates a document node tree against the schema set by
+// WithSchema, if any.
+func checkSchema(node *yaml.Node, o *options) error {
+	if o.schemaErr != nil {
+		return o.schemaErr
+	}
+	if o.schema == nil {
+		return nil
+	}
+	doc, err := schemaInstance(node)
+	if err != nil {
+		return err
+	}
+
+	err = o.schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+	var violations []SchemaViolation
+	collectViolations(verr, &violations)
+	// Properties are validated in map order, so sort for stable output.
+	slices.SortStableFunc(violations, func(a, b SchemaViolation) int {
+		return cmp.Compare(a.Pointer, b.Pointer)
+	})
+	return &SchemaError{Violations: violations}
+}
+
+// schemaInstance converts a node tree into the generic values the schema
+// validator expects. Timestamps have no JSON counterpart, so they are
+// decoded as the strings they were written as.
+func schemaInstance(node *yaml.Node) (any, error) {
+	var timestamps []*yaml.Node
+	walkNodes(node, "", func(n *yaml.Node, _ string) bool {
+		if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!timestamp" {
+			timestamps = append(timestamps, n)
+		}
+		return true
+	})
+	tags := make([]string, len(timestamps))
+	for i, n := range timestamps {
+		tags[i], n.Tag = n.Tag, "!!str"
+	}
+	defer func() {
+		for i, n := range timestamps {
+			n.Tag = tags[i]
+		}
+	}()
+	return valueFromNode(node)
+}
+
+// collectViolations flattens a validation error into its leaf causes,
+// which are the individual keywords that failed.
+func collectViolations(e *jsonschema.ValidationError, out *[]SchemaViolation) {
+	if len(e.Causes) > 0 {
+		for _, cause := range e.Causes {
+			collectViolations(cause, out)
+		}
+		return
+	}
+	_, fragment, _ := strings.Cut(e.SchemaURL, "#")
+	*out = append(*out, SchemaViolation{
+		Pointer: jsonPointer(e.InstanceLocation),
+		Keyword: fragment + jsonPointer(e.ErrorKind.KeywordPath()),
+		Message: e.BasicOutput().Error.String(),
+	})
+}
+
+// jsonPointer joins reference tokens into a JSON pointer, escaping "~" and
+// "/" as RFC 6901 requires.
+func jsonPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package load
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testServiceSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["name", "servers"],
+  "properties": {
+    "name": {"type": "string", "minLength": 3},
+    "contact": {"type": "string", "format": "email"},
+    "released": {"type": "string", "format": "date"},
+    "servers": {"type": "array", "items": {"$ref": "#/$defs/server"}},
+    "backend": {
+      "oneOf": [
+        {"type": "string"},
+        {"type": "object", "required": ["url"]}
+      ]
+    },
+    "labels": {
+      "type": "object",
+      "patternProperties": {"^[a-z]+$": {"type": "string"}},
+      "additionalProperties": false
+    }
+  },
+  "$defs": {
+    "server": {
+      "type": "object",
+      "required": ["host"],
+      "properties": {
+        "host": {"type": "string"},
+        "port": {"type": "integer", "maximum": 65535}
+      }
+    }
+  }
+}`
+
+func TestWithSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{
+			name:   "JSON",
+			format: FormatJSON,
+			input:  `{"name": "api", "contact": "ops@example.com", "servers": [{"host": "a", "port": 80}], "backend": "svc"}`,
+		},
+		{
+			name:   "YAML",
+			format: FormatYAML,
+			input: `name: api
+released: 2024-05-01
+servers:
+  - host: a
+    port: 80
+backend:
+  url: http://svc
+labels:
+  team: core
+`,
+		},
+		{
+			name:   "TOML",
+			format: FormatTOML,
+			input: `name = "api"
+released = 2024-05-01
+
+[[servers]]
+host = "a"
+port = 80
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode[map[string]any](tt.format, strings.NewReader(tt.input), []Option{WithSchema([]byte(testServiceSchema))})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWithSchema_Violations(t *testing.T) {
+	input := `name: ab
+contact: not-an-email
+released: 2024-13-45
+servers:
+  - port: 80
+  - host: b
+    port: 70000
+backend: 42
+labels:
+  Team: core
+`
+	_, err := FromYAML[map[string]any](strings.NewReader(input), WithSchema([]byte(testServiceSchema)))
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+
+	var found []string
+	for _, v := range schemaErr.Violations {
+		found = append(found, v.Pointer+" "+v.Keyword)
+	}
+	assert.Equal(t, []string{
+		"/backend /properties/backend/oneOf/0/type",
+		"/backend /properties/backend/oneOf/1/type",
+		"/contact /properties/contact/format",
+		"/labels /properties/labels/additionalProperties",
+		"/name /properties/name/minLength",
+		"/released /properties/released/format",
+		"/servers/0 /$defs/server/required",
+		"/servers/1/port /$defs/server/properties/port/maximum",
+	}, found)
+	assert.Contains(t, err.Error(), "schema validation failed:")
+	assert.Contains(t, err.Error(), "/servers/1/port: maximum: got 70,000, want 65,535")
+}
+
+func TestWithSchema_RootViolation(t *testing.T) {
+	_, err := FromJSON[any](strings.NewReader(`[1, 2]`), WithSchema([]byte(testServiceSchema)))
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	require.Len(t, schemaErr.Violations, 1)
+	assert.Equal(t, "", schemaErr.Violations[0].Pointer)
+	assert.Contains(t, err.Error(), "(root): ")
+}
+
+func TestWithSchema_EscapedPointer(t *testing.T) {
+	schema := `{"additionalProperties": {"type": "integer"}}`
+	_, err := FromJSON[map[string]any](strings.NewReader(`{"a/b~c": "x"}`), WithSchema([]byte(schema)))
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	require.Len(t, schemaErr.Violations, 1)
+	assert.Equal(t, "/a~1b~0c", schemaErr.Violations[0].Pointer)
+}
+
+func TestWithSchema_InvalidSchema(t *testing.T) {
+	_, err := FromJSON[map[string]any](strings.NewReader(`{}`), WithSchema([]byte(`{"type": 12}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid schema")
+}
+
+func TestWithSchema_AfterInterpolation(t *testing.T) {
+	input := "name: ${NAME}\nservers: []\n"
+	lookup := testLookup(map[string]string{"NAME": "x"})
+	_, err := FromYAML[map[string]any](strings.NewReader(input), ExpandEnv(lookup), WithSchema([]byte(testServiceSchema)))
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, "/name", schemaErr.Violations[0].Pointer)
+}
+
+func TestWithSchema_GeneratedSchema(t *testing.T) {
+	schema, err := Schema[testValidatedConfig]()
+	require.NoError(t, err)
+
+	_, err = FromJSON[testValidatedConfig](strings.NewReader(`{"name": "api", "mode": "slow", "servers": []}`), WithSchema(schema))
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	var pointers []string
+	for _, v := range schemaErr.Violations {
+		pointers = append(pointers, v.Pointer)
+	}
+	assert.Equal(t, []string{"/mode", "/servers"}, pointers)
+}
@@ -0,0 +1,512 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jgfranco17/dev-tooling-go/fileutils"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Origin records which source supplied a resolved configuration value.
+type Origin struct {
+	// Source is the name of the layer, e.g. "defaults", "user" or "env".
+	Source string
+	// Detail locates the value inside the layer, e.g. a file path,
+	// an environment variable or a flag name.
+	Detail string
+}
+
+func (o Origin) String() string {
+	if o.Detail == "" {
+		return o.Source
+	}
+	return fmt.Sprintf("%s (%s)", o.Source, o.Detail)
+}
+
+// Source supplies a single layer of a layered configuration.
+type Source struct {
+	name string
+	load func(t reflect.Type) (*layer, error)
+}
+
+// layer holds the values a source supplied, keyed like the target type's
+// `yaml` tags, along with the detail of every leaf value it set.
+type layer struct {
+	values  map[string]any
+	details map[string]string
+	detail  string
+}
+
+// detailFor returns the provenance detail recorded for a path or for the
+// closest of its parents.
+func (l *layer) detailFor(path string) string {
+	for p := path; p != ""; p = parentPath(p) {
+		if d, ok := l.details[p]; ok {
+			return d
+		}
+	}
+	return l.detail
+}
+
+// DefaultsSource supplies the values of a struct holding the defaults.
+func DefaultsSource(defaults any) Source {
+	return Source{
+		name: "defaults",
+		load: func(reflect.Type) (*layer, error) {
+			node := &yaml.Node{}
+			if err := node.Encode(defaults); err != nil {
+				return nil, err
+			}
+			values, err := mappingFromNode(node)
+			if err != nil {
+				return nil, err
+			}
+			return &layer{values: values}, nil
+		},
+	}
+}
+
+// FileSource supplies the contents of a JSON, YAML or TOML file on disk,
+// such as a system-wide or per-user configuration file. The layer is
+// skipped when the file does not exist.
+func FileSource(name string, path string) Source {
+	return Source{
+		name: name,
+		load: func(reflect.Type) (*layer, error) {
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return fileLayer(path, data)
+		},
+	}
+}
+
+// FSSource supplies the contents of a JSON, YAML or TOML file inside a
+// filesystem. The layer is skipped when the file does not exist.
+func FSSource(name string, fsys fs.FS, path string) Source {
+	return Source{
+		name: name,
+		load: func(reflect.Type) (*layer, error) {
+			data, err := fs.ReadFile(fsys, path)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return fileLayer(path, data)
+		},
+	}
+}
+
+// ProjectSource supplies a project file located relative to the root
+// directory stored in the context by fileutils.ApplyRootDirToContext.
+func ProjectSource(ctx context.Context, path string) Source {
+	return FSSource("project", fileutils.RootDirFromContext(ctx), path)
+}
+
+// EnvSource supplies environment variables named as in FromEnv: after the
+// prefix and the field path of the target type, e.g. APP_DB_MAX_CONNS for
+// the MaxConns field of the DB struct with prefix "APP", or after the
+// `env` tag of a field, where a tag on a nested struct replaces the
+// prefix of its fields. Slices are read as comma-separated lists and maps
+// as comma-separated k=v pairs. Variables are resolved through lookup, or
+// os.LookupEnv when it is nil.
+func EnvSource(prefix string, lookup LookupFunc) Source {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return Source{
+		name: "env",
+		load: func(t reflect.Type) (*layer, error) {
+			l := &layer{values: map[string]any{}, details: map[string]string{}}
+			for t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Struct {
+				return l, nil
+			}
+			for _, f := range envFields(t, prefix) {
+				keys, ok := documentKeys(t, f.index)
+				if !ok {
+					continue
+				}
+				value, ok := lookup(f.name)
+				if !ok {
+					continue
+				}
+				leaf := newLeafField(keys, f.field.Type)
+				if err := setLeaf(l, leaf, splitList(value), value, f.name); err != nil {
+					return nil, fmt.Errorf("%s: %w", f.name, err)
+				}
+			}
+			return l, nil
+		},
+	}
+}
+
+// FlagSource supplies the flags that were explicitly set on the command
+// line. A flag is matched to a field when its name equals the field path
+// with dots replaced by dashes, e.g. --db-host for `db.host`.
+func FlagSource(flags *pflag.FlagSet) Source {
+	return Source{
+		name: "flags",
+		load: func(t reflect.Type) (*layer, error) {
+			l := &layer{values: map[string]any{}, details: map[string]string{}}
+			for _, leaf := range leafFields(t, nil) {
+				name := strings.Join(leaf.keys, "-")
+				flag := flags.Lookup(name)
+				if flag == nil || !flag.Changed {
+					continue
+				}
+				var items []string
+				if slice, ok := flag.Value.(pflag.SliceValue); ok {
+					items = slice.GetSlice()
+				}
+				if err := setLeaf(l, leaf, items, flag.Value.String(), "--"+name); err != nil {
+					return nil, fmt.Errorf("--%s: %w", name, err)
+				}
+			}
+			return l, nil
+		},
+	}
+}
+
+// Layered resolves a configuration from several sources. Sources are
+// applied in order, each one overriding the previous ones; the usual
+// precedence is defaults, system file, user file, project file,
+// environment and finally flags.
+//
+// Mappings are merged key by key. Slices are replaced by default, or
+// appended to when the field carries a `merge:"append"` struct tag.
+// Document keys follow the `yaml` struct tags of T.
+type Layered[T any] struct {
+	sources []Source
+}
+
+// NewLayered creates a resolver over the given sources, lowest precedence first.
+func NewLayered[T any](sources ...Source) *Layered[T] {
+	return &Layered[T]{sources: sources}
+}
+
+// Resolved is a resolved configuration along with the provenance of
+// each of its values.
+type Resolved[T any] struct {
+	Value   T
+	origins map[string]Origin
+}
+
+// Resolve loads every source, merges them and decodes the result into T.
+func (l *Layered[T]) Resolve(opts ...Option) (*Resolved[T], error) {
+	t := reflect.TypeFor[T]()
+	resolved := &Resolved[T]{origins: map[string]Origin{}}
+	merged := map[string]any{}
+	for _, source := range l.sources {
+		lay, err := source.load(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s configuration: %w", source.name, err)
+		}
+		if lay == nil {
+			continue
+		}
+		resolved.merge(merged, lay.values, "", t, source.name, lay)
+	}
+
+	node, err := nodeFromValue(merged)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeNode(node, &resolved.Value, newOptions(opts)); err != nil {
+		return nil, fmt.Errorf("failed to decode layered configuration: %w", err)
+	}
+	return resolved, nil
+}
+
+// Origin reports which source supplied the value at a document path such
+// as "db.host" or "servers[0]". Paths inside a value that was supplied as
+// a whole report the origin of that value.
+func (r *Resolved[T]) Origin(path string) (Origin, bool) {
+	for p := path; p != ""; p = parentPath(p) {
+		if origin, ok := r.origins[p]; ok {
+			return origin, true
+		}
+	}
+	return Origin{}, false
+}
+
+// Origins returns the origin of every resolved leaf value by path.
+func (r *Resolved[T]) Origins() map[string]Origin {
+	return maps.Clone(r.origins)
+}
+
+// Explain describes where the value at a document path came from.
+func (r *Resolved[T]) Explain(path string) string {
+	origin, ok := r.Origin(path)
+	if !ok {
+		return fmt.Sprintf("%s is not set by any source", path)
+	}
+	return fmt.Sprintf("%s is set by %s", path, origin)
+}
+
+// merge deep-merges src into dst, recording the origin of every value.
+func (r *Resolved[T]) merge(dst, src map[string]any, path string, t reflect.Type, source string, lay *layer) {
+	for _, key := range slices.Sorted(maps.Keys(src)) {
+		value := src[key]
+		p := joinPath(path, key)
+		field, ft := fieldType(t, key)
+
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			r.merge(dstMap, srcMap, p, ft, source, lay)
+			continue
+		}
+
+		srcSlice, srcIsSlice := value.([]any)
+		dstSlice, dstIsSlice := dst[key].([]any)
+		if srcIsSlice && dstIsSlice && field != nil && field.Tag.Get("merge") == "append" {
+			dst[key] = append(dstSlice, srcSlice...)
+			for i, item := range srcSlice {
+				r.record(indexPath(p, len(dstSlice)+i), item, source, lay)
+			}
+			continue
+		}
+
+		for existing := range r.origins {
+			if existing == p || isChildPath(existing, p) {
+				delete(r.origins, existing)
+			}
+		}
+		dst[key] = value
+		r.record(p, value, source, lay)
+	}
+}
+
+// record stores the origin of every leaf of a value.
+func (r *Resolved[T]) record(path string, value any, source string, lay *layer) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			r.record(joinPath(path, key), item, source, lay)
+		}
+	case []any:
+		r.origins[path] = Origin{Source: source, Detail: lay.detailFor(path)}
+		for i, item := range v {
+			r.record(indexPath(path, i), item, source, lay)
+		}
+	default:
+		r.origins[path] = Origin{Source: source, Detail: lay.detailFor(path)}
+	}
+}
+
+// fieldType resolves the struct field and type that a document key
+// addresses within t, if any.
+func fieldType(t reflect.Type, key string) (*reflect.StructField, reflect.Type) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil, nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := structFields(t, "yaml")
+		if f, ok := lookupField(fields, key, "yaml"); ok {
+			return &f.field, f.field.Type
+		}
+		if f, ok := inlineField(fields); ok {
+			return nil, f.field.Type.Elem()
+		}
+	case reflect.Map:
+		return nil, t.Elem()
+	}
+	return nil, nil
+}
+
+// leafField is a field that environment variables and flags can set.
+type leafField struct {
+	keys    []string
+	slice   bool
+	mapping bool
+}
+
+// newLeafField describes a field of the given type at a document path.
+func newLeafField(keys []string, t reflect.Type) leafField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return leafField{
+		keys:    keys,
+		slice:   t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8,
+		mapping: t.Kind() == reflect.Map,
+	}
+}
+
+// leafFields lists the fields of t that hold scalars or lists of scalars.
+func leafFields(t reflect.Type, keys []string) []leafField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || hasCustomUnmarshaler(t) {
+		if len(keys) == 0 {
+			return nil
+		}
+		return []leafField{newLeafField(keys, t)}
+	}
+	var leaves []leafField
+	for _, f := range structFields(t, "yaml") {
+		if f.field.Type.Kind() == reflect.Map {
+			continue
+		}
+		leaves = append(leaves, leafFields(f.field.Type, append(slices.Clone(keys), f.key))...)
+	}
+	return leaves
+}
+
+// documentKeys maps the index path of a struct field onto its document
+// keys, reporting false when the field has no key of its own.
+func documentKeys(t reflect.Type, index []int) ([]string, bool) {
+	var keys []string
+	for len(index) > 0 {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+		fields := structFields(t, "yaml")
+		i := slices.IndexFunc(fields, func(f structField) bool {
+			return !f.inline && len(f.index) <= len(index) && slices.Equal(f.index, index[:len(f.index)])
+		})
+		if i < 0 {
+			return nil, false
+		}
+		f := fields[i]
+		keys = append(keys, f.key)
+		index = index[len(f.index):]
+		t = f.field.Type
+	}
+	return keys, true
+}
+
+// setLeaf stores a raw textual value into a layer. Scalars are stored as
+// untagged YAML nodes so that they are typed by the field they decode into.
+func setLeaf(l *layer, leaf leafField, items []string, value string, detail string) error {
+	var v any = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	switch {
+	case leaf.slice:
+		list := make([]any, 0, len(items))
+		for _, item := range items {
+			list = append(list, &yaml.Node{Kind: yaml.ScalarNode, Value: strings.TrimSpace(item)})
+		}
+		v = list
+	case leaf.mapping:
+		pairs, err := splitPairs(value)
+		if err != nil {
+			return err
+		}
+		entries := make(map[string]any, len(pairs))
+		for _, pair := range pairs {
+			entries[pair[0]] = &yaml.Node{Kind: yaml.ScalarNode, Value: pair[1]}
+		}
+		v = entries
+	}
+
+	m := l.values
+	for _, key := range leaf.keys[:len(leaf.keys)-1] {
+		child, ok := m[key].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[key] = child
+		}
+		m = child
+	}
+	m[leaf.keys[len(leaf.keys)-1]] = v
+	l.details[strings.Join(leaf.keys, ".")] = detail
+	return nil
+}
+
+// envName derives the environment variable name of a field path.
+func envName(prefix string, keys []string) string {
+	parts := make([]string, 0, len(keys)+1)
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	parts = append(parts, keys...)
+	name := strings.ToUpper(strings.Join(parts, "_"))
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// fileLayer decodes a configuration file into a layer.
+func fileLayer(path string, data []byte) (*layer, error) {
+	data, err := decompressBytes(data, newOptions(nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		if format, err = DetectFormat(data); err != nil {
+			return nil, err
+		}
+	}
+	node, err := codecs[format].parse(data, newOptions(nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	values, err := mappingFromNode(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return &layer{values: values, detail: path}, nil
+}
+
+// mappingFromNode converts a node tree into a generic mapping.
+func mappingFromNode(node *yaml.Node) (map[string]any, error) {
+	if isEmptyDocument(node) {
+		return map[string]any{}, nil
+	}
+	v, err := valueFromNode(node)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a mapping at the document root, got %T", v)
+	}
+	return m, nil
+}
+
+// parentPath strips the last key or index from a document path.
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+// isChildPath reports whether path lies strictly below parent.
+func isChildPath(path string, parent string) bool {
+	return strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[")
+}
@@ -0,0 +1,212 @@
+package load
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jgfranco17/dev-tooling-go/fileutils"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLayeredDB struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+type testLayeredConfig struct {
+	Name    string            `yaml:"name"`
+	Timeout time.Duration     `yaml:"timeout"`
+	DB      testLayeredDB     `yaml:"db"`
+	Tags    []string          `yaml:"tags"`
+	Plugins []string          `yaml:"plugins" merge:"append"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+func TestLayered_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	systemPath := filepath.Join(dir, "system.yaml")
+	userPath := filepath.Join(dir, "user.json")
+	require.NoError(t, os.WriteFile(systemPath, []byte("name: system\ndb:\n  host: db.system\n  port: 5432\ntags: [a, b]\nplugins: [core]\nlabels:\n  tier: web\n"), 0o600))
+	require.NoError(t, os.WriteFile(userPath, []byte(`{"db":{"host":"db.user"},"tags":["c"],"plugins":["extra"],"labels":{"team":"core"}}`), 0o600))
+
+	rootFS := fstest.MapFS{
+		".app.toml": &fstest.MapFile{Data: []byte("name = \"project\"\n")},
+	}
+	ctx := fileutils.ApplyRootDirToContext(context.Background(), rootFS)
+
+	env := testLookup(map[string]string{"APP_DB_PORT": "6543", "APP_PLUGINS": "env1,env2"})
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("db-host", "", "database host")
+	flags.String("name", "", "name")
+	require.NoError(t, flags.Parse([]string{"--db-host", "db.flag"}))
+
+	resolved, err := NewLayered[testLayeredConfig](
+		DefaultsSource(testLayeredConfig{Name: "default", Timeout: 5 * time.Second}),
+		FileSource("system", systemPath),
+		FileSource("user", userPath),
+		FileSource("missing", filepath.Join(dir, "missing.yaml")),
+		ProjectSource(ctx, ".app.toml"),
+		EnvSource("APP", env),
+		FlagSource(flags),
+	).Resolve()
+	require.NoError(t, err)
+
+	assert.Equal(t, testLayeredConfig{
+		Name:    "project",
+		Timeout: 5 * time.Second,
+		DB:      testLayeredDB{Host: "db.flag", Port: 6543},
+		Tags:    []string{"c"},
+		Plugins: []string{"core", "extra", "env1", "env2"},
+		Labels:  map[string]string{"tier": "web", "team": "core"},
+	}, resolved.Value)
+
+	tests := []struct {
+		path string
+		want Origin
+	}{
+		{path: "name", want: Origin{Source: "project", Detail: ".app.toml"}},
+		{path: "timeout", want: Origin{Source: "defaults"}},
+		{path: "db.host", want: Origin{Source: "flags", Detail: "--db-host"}},
+		{path: "db.port", want: Origin{Source: "env", Detail: "APP_DB_PORT"}},
+		{path: "tags", want: Origin{Source: "user", Detail: userPath}},
+		{path: "tags[0]", want: Origin{Source: "user", Detail: userPath}},
+		{path: "plugins[0]", want: Origin{Source: "system", Detail: systemPath}},
+		{path: "plugins[1]", want: Origin{Source: "user", Detail: userPath}},
+		{path: "plugins[3]", want: Origin{Source: "env", Detail: "APP_PLUGINS"}},
+		{path: "labels.tier", want: Origin{Source: "system", Detail: systemPath}},
+		{path: "labels.team", want: Origin{Source: "user", Detail: userPath}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := resolved.Origin(tt.path)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.Equal(t, "db.host is set by flags (--db-host)", resolved.Explain("db.host"))
+	assert.Equal(t, "unknown is not set by any source", resolved.Explain("unknown"))
+	assert.NotContains(t, resolved.Origins(), "tags[1]")
+}
+
+func TestLayered_Errors(t *testing.T) {
+	rootFS := fstest.MapFS{
+		"broken.yaml": &fstest.MapFile{Data: []byte("name: [unclosed\n")},
+		"list.yaml":   &fstest.MapFile{Data: []byte("- a\n- b\n")},
+		"typed.yaml":  &fstest.MapFile{Data: []byte("db:\n  port: not-a-number\n")},
+	}
+
+	_, err := NewLayered[testLayeredConfig](FSSource("project", rootFS, "broken.yaml")).Resolve()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "project")
+
+	_, err = NewLayered[testLayeredConfig](FSSource("project", rootFS, "list.yaml")).Resolve()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mapping")
+
+	_, err = NewLayered[testLayeredConfig](FSSource("project", rootFS, "typed.yaml")).Resolve()
+	assert.Error(t, err)
+}
+
+func TestLayered_Options(t *testing.T) {
+	rootFS := fstest.MapFS{
+		"config.yaml": &fstest.MapFile{Data: []byte("name: ${NAME}\nnmae: typo\n")},
+	}
+
+	_, err := NewLayered[testLayeredConfig](FSSource("project", rootFS, "config.yaml")).Resolve(Strict())
+	var unknown *UnknownFieldsError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, []string{"nmae"}, unknown.Paths)
+
+	resolved, err := NewLayered[testLayeredConfig](FSSource("project", rootFS, "config.yaml")).Resolve(ExpandEnv(testLookup(map[string]string{"NAME": "expanded"})))
+	require.NoError(t, err)
+	assert.Equal(t, "expanded", resolved.Value.Name)
+}
+
+func TestEnvSource_MatchesFromEnv(t *testing.T) {
+	type pool struct {
+		MaxConns int `yaml:"maxConns"`
+	}
+	type store struct {
+		Host string
+		Pool pool `env:"POOL"`
+	}
+	type config struct {
+		DB     store             `yaml:"db"`
+		Labels map[string]string `yaml:"labels"`
+		Port   int               `yaml:"port" env:"PORT"`
+	}
+	env := testLookup(map[string]string{
+		"APP_DB_HOST":    "db.env",
+		"POOL_MAXCONNS":  "7",
+		"APP_LABELS":     "tier=web, team=core",
+		"PORT":           "8080",
+		"APP_DB_POOL":    "ignored",
+		"APP_DB_MAXCONN": "ignored",
+	})
+
+	fromEnv, err := FromEnv[config]("APP", env)
+	require.NoError(t, err)
+	resolved, err := NewLayered[config](EnvSource("APP", env)).Resolve()
+	require.NoError(t, err)
+
+	want := config{
+		DB:     store{Host: "db.env", Pool: pool{MaxConns: 7}},
+		Labels: map[string]string{"tier": "web", "team": "core"},
+		Port:   8080,
+	}
+	assert.Equal(t, want, fromEnv)
+	assert.Equal(t, want, resolved.Value)
+	origin, ok := resolved.Origin("db.pool.maxConns")
+	require.True(t, ok)
+	assert.Equal(t, "POOL_MAXCONNS", origin.Detail)
+	origin, ok = resolved.Origin("labels.team")
+	require.True(t, ok)
+	assert.Equal(t, "APP_LABELS", origin.Detail)
+
+	_, err = NewLayered[config](EnvSource("APP", testLookup(map[string]string{"APP_LABELS": "tier"}))).Resolve()
+	assert.ErrorContains(t, err, `APP_LABELS: invalid map entry "tier": expected k=v`)
+}
+
+func TestEnvSource_UntaggedFields(t *testing.T) {
+	type store struct {
+		MaxConns int
+	}
+	type config struct {
+		DB store
+	}
+	env := testLookup(map[string]string{"APP_DB_MAX_CONNS": "5"})
+
+	fromEnv, err := FromEnv[config]("APP", env)
+	require.NoError(t, err)
+	resolved, err := NewLayered[config](EnvSource("APP", env)).Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, 5, fromEnv.DB.MaxConns)
+	assert.Equal(t, fromEnv, resolved.Value)
+}
+
+func TestEnvSource_EmptyList(t *testing.T) {
+	type config struct {
+		Tags []string `yaml:"tags"`
+	}
+	env := testLookup(map[string]string{"APP_TAGS": ""})
+
+	fromEnv, err := FromEnv[config]("APP", env)
+	require.NoError(t, err)
+	resolved, err := NewLayered[config](EnvSource("APP", env)).Resolve()
+	require.NoError(t, err)
+	assert.Empty(t, fromEnv.Tags)
+	assert.Empty(t, resolved.Value.Tags)
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "APP_DB_HOST", envName("APP", []string{"db", "host"}))
+	assert.Equal(t, "DB_MAX_CONNS", envName("", []string{"db", "max-conns"}))
+}
@@ -0,0 +1,240 @@
+package load
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The limits below stop small untrusted documents from exhausting memory
+// through deep nesting or YAML alias expansion.
+
+// SizeLimitError is returned when the input is larger than MaxBytes.
+type SizeLimitError struct {
+	// Limit is the maximum number of bytes allowed.
+	Limit int64
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("input exceeds the limit of %d bytes", e.Limit)
+}
+
+// DepthLimitError is returned when values are nested deeper than MaxDepth.
+type DepthLimitError struct {
+	// Limit is the maximum nesting depth allowed.
+	Limit int
+	// Path locates the value that exceeds the limit.
+	Path string
+}
+
+func (e *DepthLimitError) Error() string {
+	return fmt.Sprintf("%s: nesting exceeds the depth limit of %d", limitPath(e.Path), e.Limit)
+}
+
+// AliasLimitError is returned when expanding the aliases of a YAML document
+// takes more than MaxAliases expansions.
+type AliasLimitError struct {
+	// Limit is the maximum number of alias expansions allowed.
+	Limit int
+}
+
+func (e *AliasLimitError) Error() string {
+	return fmt.Sprintf("document expands more than %d aliases", e.Limit)
+}
+
+// CollectionLimitError is returned when a mapping or sequence holds more
+// entries than MaxCollectionSize.
+type CollectionLimitError struct {
+	// Limit is the maximum number of entries allowed.
+	Limit int
+	// Size is the number of entries of the collection.
+	Size int
+	// Path locates the collection.
+	Path string
+}
+
+func (e *CollectionLimitError) Error() string {
+	return fmt.Sprintf("%s: collection of %d entries exceeds the limit of %d", limitPath(e.Path), e.Size, e.Limit)
+}
+
+// MaxBytes fails with a *SizeLimitError when the input holds more than n
+// bytes. Streaming loaders such as FromJSONLines and YAMLDocuments apply
+// the limit to the whole stream.
+func MaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// MaxDepth fails with a *DepthLimitError when mappings and sequences are
+// nested more than n levels deep, counting the levels that aliases expand
+// into.
+func MaxDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
+
+// MaxAliases fails with an *AliasLimitError when fully expanding the
+// aliases of a YAML document takes more than n expansions, including the
+// aliases nested inside the values that other aliases refer to.
+func MaxAliases(n int) Option {
+	return func(o *options) {
+		o.maxAliases = n
+	}
+}
+
+// MaxCollectionSize fails with a *CollectionLimitError when a mapping or
+// sequence holds more than n entries.
+func MaxCollectionSize(n int) Option {
+	return func(o *options) {
+		o.maxCollectionSize = n
+	}
+}
+
+// limitsNodes reports whether any limit has to be checked on the node tree.
+func (o *options) limitsNodes() bool {
+	return o.maxDepth > 0 || o.maxAliases > 0 || o.maxCollectionSize > 0
+}
+
+// readAll reads the whole input, honouring MaxBytes.
+func readAll(r io.Reader, o *options) ([]byte, error) {
+	return io.ReadAll(newLimitedReader(r, o.maxBytes))
+}
+
+// limitedReader fails once more than limit bytes have been read. A limit of
+// zero or less reads everything.
+type limitedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	exceeded  bool
+}
+
+func newLimitedReader(r io.Reader, limit int64) *limitedReader {
+	return &limitedReader{r: r, limit: limit, remaining: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.limit <= 0 {
+		return l.r.Read(p)
+	}
+	// Reading one byte past the limit tells an input that ends exactly at
+	// the limit apart from a larger one.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		n = int(l.remaining)
+		l.remaining = 0
+		l.exceeded = true
+		return n, l.err()
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// err returns the error for an input that exceeded the limit.
+func (l *limitedReader) err() error {
+	return &SizeLimitError{Limit: l.limit}
+}
+
+// checkLimits checks the structural limits of a node tree.
+func checkLimits(node *yaml.Node, o *options) error {
+	if !o.limitsNodes() {
+		return nil
+	}
+	c := &limitChecker{o: o, anchors: map[*yaml.Node]expansion{}}
+	_, err := c.walk(node, "", 0)
+	return err
+}
+
+// expansion describes a node tree with its aliases expanded.
+type expansion struct {
+	// height is the number of nested collection levels.
+	height int
+	// aliases is the number of alias expansions.
+	aliases int
+}
+
+// limitChecker walks a node tree as if its aliases were expanded. The
+// expansion of every anchored node is remembered, so that each alias costs
+// a map lookup instead of a walk over the values it refers to.
+type limitChecker struct {
+	o       *options
+	aliases int
+	anchors map[*yaml.Node]expansion
+}
+
+// walk checks a node found at the given nesting depth.
+func (c *limitChecker) walk(node *yaml.Node, path string, depth int) (expansion, error) {
+	var e expansion
+	switch node.Kind {
+	case yaml.AliasNode:
+		if node.Alias == nil {
+			return e, nil
+		}
+		target, ok := c.anchors[node.Alias]
+		if !ok {
+			// Anchors are walked before their aliases, unless the alias
+			// is nested inside the value it refers to.
+			return e, fmt.Errorf("%s: anchor %q value contains itself", limitPath(path), node.Value)
+		}
+		c.aliases += 1 + target.aliases
+		if limit := c.o.maxAliases; limit > 0 && c.aliases > limit {
+			return e, &AliasLimitError{Limit: limit}
+		}
+		if limit := c.o.maxDepth; limit > 0 && depth+target.height > limit {
+			return e, &DepthLimitError{Limit: limit, Path: path}
+		}
+		return expansion{height: target.height, aliases: 1 + target.aliases}, nil
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			child, err := c.walk(child, path, depth)
+			if err != nil {
+				return e, err
+			}
+			e = child
+		}
+	case yaml.MappingNode, yaml.SequenceNode:
+		size := len(node.Content)
+		if node.Kind == yaml.MappingNode {
+			size /= 2
+		}
+		if limit := c.o.maxCollectionSize; limit > 0 && size > limit {
+			return e, &CollectionLimitError{Limit: limit, Size: size, Path: path}
+		}
+		if limit := c.o.maxDepth; limit > 0 && depth+1 > limit {
+			return e, &DepthLimitError{Limit: limit, Path: path}
+		}
+		for i, child := range node.Content {
+			childPath := indexPath(path, i)
+			if node.Kind == yaml.MappingNode {
+				// Keys are walked too, since complex keys can nest and
+				// hold aliases like any other value.
+				childPath = joinPath(path, node.Content[i-i%2].Value)
+			}
+			child, err := c.walk(child, childPath, depth+1)
+			if err != nil {
+				return e, err
+			}
+			e.height = max(e.height, child.height)
+			e.aliases += child.aliases
+		}
+		e.height++
+	}
+	if node.Anchor != "" {
+		c.anchors[node] = e
+	}
+	return e, nil
+}
+
+// limitPath renders the path of a limit error.
+func limitPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
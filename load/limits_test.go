@@ -0,0 +1,202 @@
+package load
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBillionLaughs = `a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+h: &h [*g,*g,*g,*g,*g,*g,*g,*g,*g]
+i: &i [*h,*h,*h,*h,*h,*h,*h,*h,*h]
+`
+
+func TestMaxBytes(t *testing.T) {
+	input := `{"name": "api", "port": 8080}`
+
+	_, err := FromJSON[map[string]any](strings.NewReader(input), MaxBytes(int64(len(input))))
+	require.NoError(t, err)
+
+	_, err = FromJSON[map[string]any](strings.NewReader(input), MaxBytes(10))
+	var sizeErr *SizeLimitError
+	require.ErrorAs(t, err, &sizeErr)
+	assert.Equal(t, int64(10), sizeErr.Limit)
+	assert.EqualError(t, err, "input exceeds the limit of 10 bytes")
+}
+
+func TestMaxBytes_Files(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("name: api\nport: 8080\n"), 0o644))
+
+	_, err := FromFile[map[string]any](name, MaxBytes(8))
+	var sizeErr *SizeLimitError
+	assert.ErrorAs(t, err, &sizeErr)
+	assert.ErrorContains(t, err, "failed to load "+name)
+
+	fsys := fstest.MapFS{"config.toml": {Data: []byte("name = \"api\"\n")}}
+	_, err = FromFS[map[string]any](fsys, "config.toml", MaxBytes(4))
+	assert.ErrorAs(t, err, &sizeErr)
+
+	got, err := FromFS[map[string]any](fsys, "config.toml", MaxBytes(64))
+	require.NoError(t, err)
+	assert.Equal(t, "api", got["name"])
+}
+
+func TestMaxBytes_Streams(t *testing.T) {
+	var sizeErr *SizeLimitError
+
+	var names []string
+	var streamErr error
+	for v, err := range FromJSONLines[map[string]string](strings.NewReader("{\"n\":\"a\"}\n{\"n\":\"b\"}\n{\"n\":\"c\"}\n"), MaxBytes(20)) {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		names = append(names, v["n"])
+	}
+	assert.Equal(t, []string{"a", "b"}, names)
+	assert.ErrorAs(t, streamErr, &sizeErr)
+
+	streamErr = nil
+	for _, err := range YAMLDocuments[map[string]string](strings.NewReader("n: a\n---\nn: b\n---\nn: c\n"), MaxBytes(12)) {
+		if err != nil {
+			streamErr = err
+		}
+	}
+	assert.ErrorAs(t, streamErr, &sizeErr)
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		input    string
+		wantPath string
+	}{
+		{name: "JSON", format: FormatJSON, input: `{"a": {"b": {"c": [1]}}}`, wantPath: "a.b.c"},
+		{name: "YAML", format: FormatYAML, input: "a:\n  b:\n    c: [1]\n", wantPath: "a.b.c"},
+		{name: "TOML", format: FormatTOML, input: "[a.b]\nc = [1]\n", wantPath: "a.b.c"},
+		{name: "Alias", format: FormatYAML, input: "x: &x {b: {c: 1}}\na:\n  b: *x\n", wantPath: "a.b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode[map[string]any](tt.format, strings.NewReader(tt.input), []Option{MaxDepth(3)})
+			var depthErr *DepthLimitError
+			require.ErrorAs(t, err, &depthErr)
+			assert.Equal(t, 3, depthErr.Limit)
+			assert.Equal(t, tt.wantPath, depthErr.Path)
+
+			_, err = decode[map[string]any](tt.format, strings.NewReader(tt.input), []Option{MaxDepth(4)})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMaxAliases(t *testing.T) {
+	_, err := FromYAML[map[string]any](strings.NewReader(testBillionLaughs), MaxAliases(1000))
+	var aliasErr *AliasLimitError
+	require.ErrorAs(t, err, &aliasErr)
+	assert.Equal(t, 1000, aliasErr.Limit)
+	assert.EqualError(t, err, "document expands more than 1000 aliases")
+
+	input := "defaults: &defaults {retries: 3}\nprimary: *defaults\nbackup: *defaults\n"
+	_, err = FromYAML[map[string]any](strings.NewReader(input), MaxAliases(1))
+	assert.ErrorAs(t, err, &aliasErr)
+	got, err := FromYAML[map[string]any](strings.NewReader(input), MaxAliases(2))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"retries": 3}, got["backup"])
+}
+
+func TestMaxCollectionSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		input    string
+		wantPath string
+		wantSize int
+	}{
+		{name: "Sequence", format: FormatJSON, input: `{"ports": [80, 81, 82, 83]}`, wantPath: "ports", wantSize: 4},
+		{name: "Mapping", format: FormatYAML, input: "a: 1\nb: 2\nc: 3\nd: 4\n", wantPath: "", wantSize: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode[map[string]any](tt.format, strings.NewReader(tt.input), []Option{MaxCollectionSize(3)})
+			var sizeErr *CollectionLimitError
+			require.ErrorAs(t, err, &sizeErr)
+			assert.Equal(t, tt.wantPath, sizeErr.Path)
+			assert.Equal(t, tt.wantSize, sizeErr.Size)
+		})
+	}
+
+	_, err := FromYAML[map[string]any](strings.NewReader("a: 1\nb: 2\nc: 3\nd: 4\n"), MaxCollectionSize(3))
+	assert.EqualError(t, err, "(root): collection of 4 entries exceeds the limit of 3")
+}
+
+func TestLimits_YAMLDocuments(t *testing.T) {
+	var errs []error
+	for _, err := range YAMLDocuments[map[string]any](strings.NewReader("a: [1, 2]\n---\nb: [1, 2, 3]\n"), MaxCollectionSize(2)) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 2)
+	assert.NoError(t, errs[0])
+	var sizeErr *CollectionLimitError
+	assert.ErrorAs(t, errs[1], &sizeErr)
+}
+
+func TestLimits_BeforeTransforms(t *testing.T) {
+	called := false
+	migration := Migration{From: 0, To: 1, Func: func(map[string]any) error {
+		called = true
+		return nil
+	}}
+
+	_, err := FromYAML[map[string]any](strings.NewReader(testBillionLaughs), MaxAliases(1000), Migrate(migration))
+	var aliasErr *AliasLimitError
+	require.ErrorAs(t, err, &aliasErr)
+	assert.False(t, called, "the document must not be expanded before the limits are checked")
+
+	_, err = FromYAML[map[string]any](strings.NewReader(testBillionLaughs), MaxAliases(1000), WithMergePatch(map[string]any{"z": 1}))
+	assert.ErrorAs(t, err, &aliasErr)
+}
+
+func TestLimits_MappingKeys(t *testing.T) {
+	input := "x: &x [1, 2]\n? [*x, *x, *x]\n: value\n"
+	_, err := FromYAML[any](strings.NewReader(input), MaxAliases(2))
+	var aliasErr *AliasLimitError
+	assert.ErrorAs(t, err, &aliasErr)
+
+	_, err = FromYAML[any](strings.NewReader("? {a: {b: 1}}\n: value\n"), MaxDepth(2))
+	var depthErr *DepthLimitError
+	assert.ErrorAs(t, err, &depthErr)
+}
+
+func TestLimits_Includes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml": {Data: []byte("db: !include db.yaml\n")},
+		"db.yaml":     {Data: []byte("host: " + strings.Repeat("a", 256) + "\n")},
+		"deep.yaml":   {Data: []byte("db: !include nested.json\n")},
+		"nested.json": {Data: []byte(`{"a": {"b": {"c": 1}}}`)},
+	}
+
+	_, err := FromFS[map[string]any](fsys, "config.yaml", Includes(fsys), MaxBytes(64))
+	var sizeErr *SizeLimitError
+	require.ErrorAs(t, err, &sizeErr)
+	var includeErr *IncludeError
+	assert.ErrorAs(t, err, &includeErr)
+
+	_, err = FromFS[map[string]any](fsys, "deep.yaml", Includes(fsys), MaxDepth(2))
+	var depthErr *DepthLimitError
+	assert.ErrorAs(t, err, &depthErr)
+}
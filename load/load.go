@@ -1,24 +1,272 @@
 package load
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"reflect"
 
+	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v3"
 )
 
 // FromJSON loads and parses JSON data from the provided reader
 // into any arbitrary Go type.
-func FromJSON[T any](data io.Reader) (T, error) {
-	var v T
-	err := json.NewDecoder(data).Decode(&v)
-	return v, err
+func FromJSON[T any](data io.Reader, opts ...Option) (T, error) {
+	return decode[T](FormatJSON, data, opts)
 }
 
 // FromYAML loads and parses YAML data from the provided reader
 // into any arbitrary Go type.
-func FromYAML[T any](data io.Reader) (T, error) {
+func FromYAML[T any](data io.Reader, opts ...Option) (T, error) {
+	return decode[T](FormatYAML, data, opts)
+}
+
+// FromTOML loads and parses TOML data from the provided reader
+// into any arbitrary Go type.
+func FromTOML[T any](data io.Reader, opts ...Option) (T, error) {
+	return decode[T](FormatTOML, data, opts)
+}
+
+// decode reads a whole document in the given format and decodes it into T
+// according to the provided options.
+func decode[T any](format Format, data io.Reader, opts []Option) (T, error) {
 	var v T
-	err := yaml.NewDecoder(data).Decode(&v)
-	return v, err
+	c, err := codecFor(format)
+	if err != nil {
+		return v, err
+	}
+	o := newOptions(opts)
+
+	raw, err := readAll(data, o)
+	if err != nil {
+		return v, err
+	}
+
+	if err := decodeInto(c, raw, &v, o); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// decodeInto decodes a raw document into the value pointed to by v.
+func decodeInto(c codec, raw []byte, v any, o *options) error {
+	return annotateDecodeErrors(decodeDocument(c, raw, v, o), o.sourceName, raw)
+}
+
+// decodeDocument runs the document-level options and limits over a raw
+// document when any are set, then decodes it.
+func decodeDocument(c codec, raw []byte, v any, o *options) error {
+	if !o.transformsNodes() && !o.limitsNodes() {
+		return decodeRaw(c, raw, raw, v, o)
+	}
+
+	node, err := c.parse(raw, o)
+	if err != nil {
+		return toDecodeError(c.format, raw, raw, err)
+	}
+	if c.format == FormatYAML {
+		// Decoding the transformed tree directly keeps the original
+		// positions for error reporting.
+		return decodeNode(node, v, o)
+	}
+	if err := checkLimits(node, o); err != nil {
+		return err
+	}
+	secrets, err := transformNode(node, o)
+	if err != nil {
+		return err
+	}
+	if !o.transformsNodes() {
+		return decodeRaw(c, raw, raw, v, o)
+	}
+	decoded, err := c.render(node)
+	if err != nil {
+		return err
+	}
+	if err := decodeRaw(c, raw, decoded, v, o); err != nil {
+		return err
+	}
+	return sealSecrets(node, reflect.ValueOf(v), secrets, c.tag, "")
+}
+
+// decodeRaw decodes the decoded text of a document into v. Errors are
+// located against the original text.
+func decodeRaw(c codec, original []byte, decoded []byte, v any, o *options) error {
+	if o.checksSchema() {
+		node, err := c.parse(decoded, o)
+		if err != nil {
+			return toDecodeError(c.format, original, decoded, err)
+		}
+		if err := checkSchema(node, o); err != nil {
+			return err
+		}
+	}
+	if o.strict {
+		doc, err := c.generic(decoded)
+		if err != nil {
+			return toDecodeError(c.format, original, decoded, err)
+		}
+		if err := checkUnknownFields(doc, reflect.TypeOf(v).Elem(), c.tag); err != nil {
+			return err
+		}
+	}
+	if err := c.decode(decoded, v, o); err != nil {
+		return toDecodeError(c.format, original, decoded, err)
+	}
+	if hasDefaults(reflect.TypeOf(v)) {
+		doc, err := c.generic(decoded)
+		if err != nil {
+			return err
+		}
+		if err := setDocumentDefaults(v, doc, c.tag); err != nil {
+			return err
+		}
+	}
+	if o.validate {
+		return validate(v, c.tag)
+	}
+	return nil
+}
+
+// codec bundles the format-specific operations used by the loaders.
+type codec struct {
+	format Format
+	// tag is the struct tag the format's decoder reads field names from.
+	tag string
+	// decode decodes the first document in data into v.
+	decode func(data []byte, v any, o *options) error
+	// generic decodes the first document in data into maps, slices and scalars.
+	generic func(data []byte) (any, error)
+	// parse decodes the first document in data into a node tree.
+	parse func(data []byte, o *options) (*yaml.Node, error)
+	// render encodes a node tree back into the format.
+	render func(node *yaml.Node) ([]byte, error)
+}
+
+var codecs = map[Format]codec{
+	FormatJSON: {
+		format: FormatJSON,
+		tag:    "json",
+		decode: func(data []byte, v any, o *options) error {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			if o.strict {
+				dec.DisallowUnknownFields()
+			}
+			if err := dec.Decode(v); err != nil {
+				return err
+			}
+			return checkTrailingJSON(dec, o)
+		},
+		generic: genericJSON,
+		parse: func(data []byte, o *options) (*yaml.Node, error) {
+			var doc any
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			if err := dec.Decode(&doc); err != nil {
+				return nil, err
+			}
+			if err := checkTrailingJSON(dec, o); err != nil {
+				return nil, err
+			}
+			return nodeFromValue(doc)
+		},
+		render: func(node *yaml.Node) ([]byte, error) {
+			doc, err := valueFromNode(node)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(doc)
+		},
+	},
+	FormatYAML: {
+		format: FormatYAML,
+		tag:    "yaml",
+		decode: func(data []byte, v any, o *options) error {
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(o.strict)
+			return dec.Decode(v)
+		},
+		generic: func(data []byte) (any, error) {
+			var doc any
+			err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc)
+			return doc, err
+		},
+		parse: func(data []byte, _ *options) (*yaml.Node, error) {
+			var node yaml.Node
+			if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&node); err != nil {
+				return nil, err
+			}
+			return &node, nil
+		},
+		render: func(node *yaml.Node) ([]byte, error) {
+			return yaml.Marshal(node)
+		},
+	},
+	FormatTOML: {
+		format: FormatTOML,
+		tag:    "toml",
+		decode: func(data []byte, v any, o *options) error {
+			dec := toml.NewDecoder(bytes.NewReader(data))
+			if o.strict {
+				dec.DisallowUnknownFields()
+			}
+			return dec.Decode(v)
+		},
+		generic: genericTOML,
+		parse: func(data []byte, _ *options) (*yaml.Node, error) {
+			doc, err := genericTOML(data)
+			if err != nil {
+				return nil, err
+			}
+			return nodeFromValue(doc)
+		},
+		render: func(node *yaml.Node) ([]byte, error) {
+			doc, err := valueFromNode(node)
+			if err != nil {
+				return nil, err
+			}
+			return toml.Marshal(doc)
+		},
+	},
+}
+
+// codecFor returns the codec registered for the given format.
+func codecFor(format Format) (codec, error) {
+	c, ok := codecs[format]
+	if !ok {
+		return codec{}, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	return c, nil
+}
+
+// genericJSON decodes a JSON document, keeping numbers as json.Number so
+// that they survive a round trip without losing precision.
+func genericJSON(data []byte) (any, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(&doc)
+	return doc, err
+}
+
+// genericTOML decodes a TOML document into maps, slices and scalars.
+func genericTOML(data []byte) (any, error) {
+	var doc any
+	err := toml.NewDecoder(bytes.NewReader(data)).Decode(&doc)
+	return doc, err
+}
+
+// checkTrailingJSON rejects anything but whitespace after the document
+// read by dec in strict mode.
+func checkTrailingJSON(dec *json.Decoder, o *options) error {
+	if !o.strict {
+		return nil
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w at offset %d", ErrTrailingData, dec.InputOffset())
+	}
+	return nil
 }
@@ -3,14 +3,15 @@ package load
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 type testPerson struct {
-	Name string `json:"name" yaml:"name"`
-	Age  int    `json:"age" yaml:"age"`
+	Name string `json:"name" yaml:"name" toml:"name"`
+	Age  int    `json:"age" yaml:"age" toml:"age"`
 }
 
 func TestFromJSON_Success(t *testing.T) {
@@ -44,3 +45,52 @@ func TestFromYAML_Invalid(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, testPerson{}, got)
 }
+
+type testService struct {
+	Name     string            `toml:"name"`
+	Started  time.Time         `toml:"started"`
+	Labels   map[string]string `toml:"labels"`
+	Owner    testPerson        `toml:"owner"`
+	Backends []testBackend     `toml:"backends"`
+}
+
+type testBackend struct {
+	Host string `toml:"host"`
+	Port int    `toml:"port"`
+}
+
+func TestFromTOML_Success(t *testing.T) {
+	data := `
+name = "api"
+started = 2024-05-01T09:30:00Z
+labels = { tier = "web", team = "core" }
+owner = { name = "Ada", age = 42 }
+
+[[backends]]
+host = "10.0.0.1"
+port = 8080
+
+[[backends]]
+host = "10.0.0.2"
+port = 8081
+`
+	got, err := FromTOML[testService](strings.NewReader(data))
+
+	require.NoError(t, err)
+	assert.Equal(t, "api", got.Name)
+	assert.True(t, got.Started.Equal(time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)))
+	assert.Equal(t, map[string]string{"tier": "web", "team": "core"}, got.Labels)
+	assert.Equal(t, testPerson{Name: "Ada", Age: 42}, got.Owner)
+	assert.Equal(t, []testBackend{
+		{Host: "10.0.0.1", Port: 8080},
+		{Host: "10.0.0.2", Port: 8081},
+	}, got.Backends)
+}
+
+func TestFromTOML_Invalid(t *testing.T) {
+	invalidData := "name = "
+	got, err := FromTOML[testService](strings.NewReader(invalidData))
+
+	require.Error(t, err)
+	assert.Equal(t, testService{}, got)
+}
@@ -0,0 +1,245 @@
+package load
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// versionKey is the top-level key that holds the version of a document.
+const versionKey = "version"
+
+// Migration upgrades documents from one version of a config format to a
+// later one.
+type Migration struct {
+	// From is the version of the documents the migration applies to.
+	From int
+	// To is the version of the documents the migration produces.
+	To int
+	// Func rewrites the generic form of a document: maps with string
+	// keys, slices and scalars. The version field is updated once it
+	// returns, and a nil Func only updates the version.
+	Func func(doc map[string]any) error
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("migration from version %d to %d", m.From, m.To)
+}
+
+// Migrate upgrades documents before they are decoded, by applying the
+// migration registered for the version of the document until none is
+// left. The version is read from the top-level "version" field, and a
+// document without one is at version 0.
+func Migrate(migrations ...Migration) Option {
+	return func(o *options) {
+		o.migrations = append(o.migrations, migrations...)
+	}
+}
+
+// ReportMigrations appends every migration applied while loading to
+// applied.
+func ReportMigrations(applied *[]Migration) Option {
+	return func(o *options) {
+		o.migrated = applied
+	}
+}
+
+// MigrateFile upgrades the file at the given path in place and returns the
+// migrations that were applied. The file is only written when at least
+// one migration ran, and empty files are left untouched. The upgraded
+// document is written the way ToJSON, ToYAML or ToTOML write it, so
+// comments and key order are not kept, TOML local dates and times become
+// strings and JSON integers beyond the range of uint64 become floats. The
+// file is replaced atomically by renaming a temporary file over it. Gzip
+// files are compressed again; bzip2 files can be read but not written
+// back, so migrating one fails with ErrUnsupportedCompression.
+func MigrateFile(name string, migrations ...Migration) ([]Migration, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	compressed := compression(data[:min(len(data), len(zstdMagic))])
+	if data, err = decompressBytes(data, newOptions(nil)); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	format, err := FormatFromPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	if format == "" {
+		if format, err = DetectFormat(data); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", name, err)
+		}
+	}
+	c, err := codecFor(format)
+	if err != nil {
+		return nil, err
+	}
+	node, err := c.parse(data, newOptions(nil))
+	if err != nil {
+		return nil, annotateDecodeErrors(toDecodeError(format, data, data, err), name, data)
+	}
+	if isEmptyDocument(node) {
+		return nil, nil
+	}
+
+	doc, err := migrationDocument(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate %s: %w", name, err)
+	}
+	applied, err := migrate(doc, migrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate %s: %w", name, err)
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := encode(format, &buf, doc, nil); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	out, err := compressBytes(buf.Bytes(), compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := writeFileAtomic(name, out, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return applied, nil
+}
+
+// writeFileAtomic replaces the named file by writing data to a temporary
+// file in the same directory, syncing it and renaming it over the file.
+func writeFileAtomic(name string, data []byte, perm fs.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// migrateNode applies the registered migrations to a node tree, replacing
+// its content when any migration ran.
+func migrateNode(node *yaml.Node, o *options) error {
+	if isEmptyDocument(node) {
+		return nil
+	}
+	doc, err := migrationDocument(node)
+	if err != nil {
+		return err
+	}
+	applied, err := migrate(doc, o.migrations)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+	if o.migrated != nil {
+		*o.migrated = append(*o.migrated, applied...)
+	}
+	migrated, err := nodeFromValue(doc)
+	if err != nil {
+		return err
+	}
+	content := documentContent(node)
+	*content = *migrated
+	return nil
+}
+
+// migrationDocument converts a node tree into the generic document that
+// migrations rewrite.
+func migrationDocument(node *yaml.Node) (map[string]any, error) {
+	value, err := valueFromNode(node)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot migrate %T document: not a mapping", value)
+	}
+	return doc, nil
+}
+
+// migrate applies the chain of migrations starting at the version of a
+// document and returns the migrations that ran.
+func migrate(doc map[string]any, migrations []Migration) ([]Migration, error) {
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		if m.To <= m.From {
+			return nil, fmt.Errorf("invalid %s: versions must increase", m)
+		}
+		if _, ok := byVersion[m.From]; ok {
+			return nil, fmt.Errorf("multiple migrations from version %d", m.From)
+		}
+		byVersion[m.From] = m
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for {
+		m, ok := byVersion[version]
+		if !ok {
+			return applied, nil
+		}
+		if m.Func != nil {
+			if err := m.Func(doc); err != nil {
+				return nil, fmt.Errorf("%s: %w", m, err)
+			}
+		}
+		doc[versionKey] = m.To
+		version = m.To
+		applied = append(applied, m)
+	}
+}
+
+// documentVersion reads the version of a generic document.
+func documentVersion(doc map[string]any) (int, error) {
+	switch v := doc[versionKey].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		if v <= math.MaxInt {
+			return int(v), nil
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s %v: expected an integer", versionKey, doc[versionKey])
+}
@@ -0,0 +1,202 @@
+package load
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMigratedConfig struct {
+	Version int `json:"version" yaml:"version" toml:"version"`
+	Server  struct {
+		Host string `json:"host" yaml:"host" toml:"host"`
+		Port int    `json:"port" yaml:"port" toml:"port"`
+	} `json:"server" yaml:"server" toml:"server"`
+	Timeout string `json:"timeout" yaml:"timeout" toml:"timeout"`
+}
+
+// testMigrations moves the flat host and port keys of version 1 into a
+// server table, then adds a default timeout in version 3.
+func testMigrations() []Migration {
+	return []Migration{
+		{From: 1, To: 2, Func: func(doc map[string]any) error {
+			doc["server"] = map[string]any{"host": doc["host"], "port": doc["port"]}
+			delete(doc, "host")
+			delete(doc, "port")
+			return nil
+		}},
+		{From: 2, To: 3, Func: func(doc map[string]any) error {
+			if _, ok := doc["timeout"]; !ok {
+				doc["timeout"] = "30s"
+			}
+			return nil
+		}},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      Format
+		input       string
+		wantApplied int
+	}{
+		{name: "YAML", format: FormatYAML, input: "version: 1\nhost: localhost\nport: 8080\n", wantApplied: 2},
+		{name: "JSON", format: FormatJSON, input: `{"version": 1, "host": "localhost", "port": 8080}`, wantApplied: 2},
+		{name: "TOML", format: FormatTOML, input: "version = 1\nhost = \"localhost\"\nport = 8080\n", wantApplied: 2},
+		{name: "Partial", format: FormatYAML, input: "version: 2\nserver: {host: localhost, port: 8080}\n", wantApplied: 1},
+		{name: "Current", format: FormatYAML, input: "version: 3\nserver: {host: localhost, port: 8080}\ntimeout: 30s\n", wantApplied: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var applied []Migration
+			got, err := decode[testMigratedConfig](tt.format, strings.NewReader(tt.input),
+				[]Option{Migrate(testMigrations()...), ReportMigrations(&applied)})
+			require.NoError(t, err)
+			assert.Equal(t, 3, got.Version)
+			assert.Equal(t, "localhost", got.Server.Host)
+			assert.Equal(t, 8080, got.Server.Port)
+			assert.Equal(t, "30s", got.Timeout)
+			assert.Len(t, applied, tt.wantApplied)
+		})
+	}
+}
+
+func TestMigrate_Report(t *testing.T) {
+	var applied []Migration
+	_, err := FromYAML[testMigratedConfig](strings.NewReader("host: localhost\n"),
+		Migrate(append(testMigrations(), Migration{From: 0, To: 1})...), ReportMigrations(&applied))
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+	assert.Equal(t, "migration from version 0 to 1", applied[0].String())
+	assert.Equal(t, "migration from version 2 to 3", applied[2].String())
+}
+
+func TestMigrate_Errors(t *testing.T) {
+	failing := errors.New("boom")
+	tests := []struct {
+		name       string
+		input      string
+		migrations []Migration
+		wantErr    string
+	}{
+		{
+			name:       "FailingMigration",
+			input:      "version: 1\n",
+			migrations: []Migration{{From: 1, To: 2, Func: func(map[string]any) error { return failing }}},
+			wantErr:    "migration from version 1 to 2: boom",
+		},
+		{
+			name:       "Backwards",
+			input:      "version: 2\n",
+			migrations: []Migration{{From: 2, To: 1}},
+			wantErr:    "invalid migration from version 2 to 1: versions must increase",
+		},
+		{
+			name:       "Duplicate",
+			input:      "version: 1\n",
+			migrations: []Migration{{From: 1, To: 2}, {From: 1, To: 3}},
+			wantErr:    "multiple migrations from version 1",
+		},
+		{
+			name:       "InvalidVersion",
+			input:      "version: v2\n",
+			migrations: testMigrations(),
+			wantErr:    "invalid version v2: expected an integer",
+		},
+		{
+			name:       "NotAMapping",
+			input:      "- a\n- b\n",
+			migrations: testMigrations(),
+			wantErr:    "cannot migrate []interface {} document: not a mapping",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromYAML[any](strings.NewReader(tt.input), Migrate(tt.migrations...))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("# old format\nversion: 1\nhost: localhost\nport: 8080\n"), 0o600))
+
+	applied, err := MigrateFile(name, testMigrations()...)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "server:\n  host: localhost\n  port: 8080\ntimeout: 30s\nversion: 3\n", string(data))
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	applied, err = MigrateFile(name, testMigrations()...)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	got, err := FromFile[testMigratedConfig](name)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Version)
+	assert.Equal(t, "localhost", got.Server.Host)
+}
+
+func TestMigrateFile_TOML(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.toml")
+	require.NoError(t, os.WriteFile(name, []byte("version = 1\nhost = \"localhost\"\nport = 8080\n"), 0o644))
+
+	_, err := MigrateFile(name, testMigrations()...)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "timeout = '30s'\nversion = 3\n\n[server]\nhost = 'localhost'\nport = 8080\n", string(data))
+}
+
+func TestMigrateFile_Types(t *testing.T) {
+	dir := t.TempDir()
+	jsonName := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(jsonName, []byte(`{"id": 18446744073709551615, "min": -9223372036854775808}`), 0o600))
+	tomlName := filepath.Join(dir, "config.toml")
+	require.NoError(t, os.WriteFile(tomlName, []byte("day = 2024-01-02\nat = 2024-01-02T10:00:00Z\n"), 0o600))
+
+	for _, name := range []string{jsonName, tomlName} {
+		_, err := MigrateFile(name, Migration{From: 0, To: 1})
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(jsonName)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id": 18446744073709551615`)
+	assert.Contains(t, string(data), `"min": -9223372036854775808`)
+	data, err = os.ReadFile(tomlName)
+	require.NoError(t, err)
+	assert.Equal(t, "at = 2024-01-02T10:00:00Z\nday = '2024-01-02'\nversion = 1\n", string(data))
+}
+
+func TestMigrateFile_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("version: 1\nhost: localhost\n"), 0o640))
+
+	_, err := MigrateFile(name, testMigrations()...)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "config.yaml", entries[0].Name())
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+}
@@ -0,0 +1,56 @@
+package load
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Option configures the behaviour of the loaders.
+type Option func(*options)
+
+// options holds the settings collected from the provided Option values.
+type options struct {
+	strict            bool
+	skipMalformed     bool
+	malformed         *[]*LineError
+	validate          bool
+	lookupEnv         LookupFunc
+	sourceName        string
+	schema            *jsonschema.Schema
+	schemaErr         error
+	pollInterval      time.Duration
+	includeFS         fs.FS
+	indent            int
+	hasIndent         bool
+	sortKeys          bool
+	omitZero          bool
+	multiDocument     bool
+	secrets           SecretResolvers
+	maxBytes          int64
+	maxDepth          int
+	maxAliases        int
+	maxCollectionSize int
+	migrations        []Migration
+	migrated          *[]Migration
+	matchKey          string
+	patches           []func(doc any) (any, error)
+}
+
+// newOptions applies the provided options on top of the defaults.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Strict rejects documents containing keys that do not map onto the
+// target type, as well as trailing data after the first JSON document.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
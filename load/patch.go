@@ -0,0 +1,457 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PatchError reports a JSON Patch operation that could not be applied.
+type PatchError struct {
+	// Index is the position of the operation in the patch.
+	Index int
+	// Op is the name of the operation.
+	Op string
+	// Path is the JSON pointer the operation targets.
+	Path string
+	// Err is the reason the operation failed.
+	Err error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch operation %d (%s %s): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// WithMergePatch applies a JSON Merge Patch (RFC 7386) to documents before
+// they are decoded: mappings in the patch are merged recursively, null
+// values remove keys and any other value replaces the target. The patch is
+// a generic document or any value that ToJSON can encode.
+func WithMergePatch(patch any) Option {
+	return func(o *options) {
+		o.patches = append(o.patches, func(doc any) (any, error) {
+			return applyMergePatch(doc, patch)
+		})
+	}
+}
+
+// WithPatch applies JSON Patch (RFC 6902) operations to documents before
+// they are decoded. Patches provided through WithPatch and WithMergePatch
+// are applied in the order the options are given.
+func WithPatch(ops ...PatchOp) Option {
+	return func(o *options) {
+		o.patches = append(o.patches, func(doc any) (any, error) {
+			return applyPatch(doc, ops)
+		})
+	}
+}
+
+// ReadPatch reads the JSON Patch operations of a JSON or YAML document,
+// such as a file passed with --patch-file.
+func ReadPatch(r io.Reader) ([]PatchOp, error) {
+	var items []map[string]any
+	if err := yaml.NewDecoder(r).Decode(&items); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	ops := make([]PatchOp, 0, len(items))
+	for i, item := range items {
+		op, _ := item["op"].(string)
+		path, ok := item["path"].(string)
+		if !ok {
+			return nil, &PatchError{Index: i, Op: op, Err: errors.New(`missing "path"`)}
+		}
+		from, _ := item["from"].(string)
+		value, hasValue := item["value"]
+		switch op {
+		case "add", "replace", "test":
+			if !hasValue {
+				return nil, &PatchError{Index: i, Op: op, Path: path, Err: errors.New(`missing "value"`)}
+			}
+		case "move", "copy":
+			if _, ok := item["from"].(string); !ok {
+				return nil, &PatchError{Index: i, Op: op, Path: path, Err: errors.New(`missing "from"`)}
+			}
+		}
+		ops = append(ops, PatchOp{Op: op, Path: path, From: from, Value: normalizeValue(value)})
+	}
+	return ops, nil
+}
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7386) to a document and
+// returns the patched document in its generic form. Both are generic
+// documents or values that ToJSON can encode; doc is not modified.
+func ApplyMergePatch(doc, patch any) (any, error) {
+	target, err := genericValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	return applyMergePatch(target, patch)
+}
+
+// applyMergePatch applies a merge patch to a generic document.
+func applyMergePatch(doc, patch any) (any, error) {
+	changes, err := genericValue(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return mergePatch(doc, changes), nil
+}
+
+// mergePatch merges a generic patch into a generic target.
+func mergePatch(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]any)
+	if !ok {
+		merged = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = mergePatch(merged[key], value)
+	}
+	return merged
+}
+
+// ApplyPatch applies JSON Patch (RFC 6902) operations to a document and
+// returns the patched document in its generic form. The document is a
+// generic document or any value that ToJSON can encode, and is not
+// modified. Operations are applied in order, and the first one that fails
+// is reported as a *PatchError.
+func ApplyPatch(doc any, ops []PatchOp) (any, error) {
+	patched, err := genericValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	return applyPatch(patched, ops)
+}
+
+// applyPatch applies JSON Patch operations to a generic document.
+func applyPatch(patched any, ops []PatchOp) (any, error) {
+	var err error
+	for i, op := range ops {
+		if patched, err = applyOp(patched, op); err != nil {
+			return nil, &PatchError{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+	}
+	return patched, nil
+}
+
+// applyOp applies a single operation to a generic document.
+func applyOp(doc any, op PatchOp) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := genericValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return addValue(doc, path, value)
+		case "replace":
+			return replaceValue(doc, path, value)
+		}
+		current, err := pointerValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(current, value) {
+			return nil, fmt.Errorf("test failed: value is %s, expected %s", diffText(current), diffText(value))
+		}
+		return doc, nil
+	case "remove":
+		return removeValue(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		value, err := pointerValue(doc, from)
+		if err != nil {
+			return nil, fmt.Errorf("from %s: %w", op.From, err)
+		}
+		if op.Op == "copy" {
+			return addValue(doc, path, copyValue(value))
+		}
+		if op.Path == op.From {
+			return doc, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("cannot move %s into itself", op.From)
+		}
+		if doc, err = removeValue(doc, from); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// addValue adds a value to a mapping, inserts it into a sequence or
+// appends it for the "-" index.
+func addValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, path, func(parent any, token string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[token] = value
+			return p, nil
+		case []any:
+			if token == "-" {
+				return append(p, value), nil
+			}
+			i, err := arrayIndex(token, len(p)+1)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Insert(p, i, value), nil
+		default:
+			return nil, errors.New("parent is not a mapping or sequence")
+		}
+	})
+}
+
+// removeValue removes an existing value.
+func removeValue(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return updateParent(doc, path, func(parent any, token string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, ok := p[token]; !ok {
+				return nil, ErrPathNotFound
+			}
+			delete(p, token)
+			return p, nil
+		case []any:
+			i, err := arrayIndex(token, len(p))
+			if err != nil {
+				return nil, err
+			}
+			return slices.Delete(p, i, i+1), nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+// replaceValue replaces an existing value.
+func replaceValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, path, func(parent any, token string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, ok := p[token]; !ok {
+				return nil, ErrPathNotFound
+			}
+			p[token] = value
+			return p, nil
+		case []any:
+			i, err := arrayIndex(token, len(p))
+			if err != nil {
+				return nil, err
+			}
+			p[i] = value
+			return p, nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+// updateParent replaces the container holding the last token of a path
+// with the result of fn, and returns the updated document.
+func updateParent(doc any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	switch d := doc.(type) {
+	case map[string]any:
+		child, ok := d[path[0]]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		updated, err := updateParent(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[path[0]] = updated
+		return d, nil
+	case []any:
+		i, err := arrayIndex(path[0], len(d))
+		if err != nil {
+			return nil, err
+		}
+		updated, err := updateParent(d[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = updated
+		return d, nil
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+// pointerValue returns the value a parsed JSON pointer refers to.
+func pointerValue(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch d := doc.(type) {
+		case map[string]any:
+			child, ok := d[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			doc = child
+		case []any:
+			i, err := arrayIndex(token, len(d))
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer splits a JSON pointer (RFC 6901) into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses the index of a sequence item, which must be below n.
+func arrayIndex(token string, n int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i >= n {
+		return 0, fmt.Errorf("%w: index %s out of range", ErrPathNotFound, token)
+	}
+	return i, nil
+}
+
+// copyValue copies the mappings and sequences of a generic value.
+func copyValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for key, item := range val {
+			out[key] = copyValue(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = copyValue(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+// jsonEqual compares generic values the way JSON does, treating numbers
+// of different Go types as equal when their values are.
+func jsonEqual(a, b any) bool {
+	if x, ok := jsonNumber(a); ok {
+		y, ok := jsonNumber(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, jsonEqual)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// jsonNumber converts a generic number to a float64.
+func jsonNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// patchNode applies the registered patches to a node tree.
+func patchNode(node *yaml.Node, o *options) error {
+	var doc any
+	if !isEmptyDocument(node) {
+		var err error
+		if doc, err = valueFromNode(node); err != nil {
+			return err
+		}
+	}
+	for _, patch := range o.patches {
+		var err error
+		if doc, err = patch(doc); err != nil {
+			return err
+		}
+	}
+	patched, err := nodeFromValue(doc)
+	if err != nil {
+		return err
+	}
+	if node.Kind == yaml.DocumentNode {
+		node.Content = []*yaml.Node{patched}
+		return nil
+	}
+	*node = *patched
+	return nil
+}
@@ -0,0 +1,255 @@
+package load
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPatchDocument() map[string]any {
+	return map[string]any{
+		"name":    "api",
+		"labels":  map[string]any{"tier": "web", "team": "core"},
+		"servers": []any{map[string]any{"host": "a", "port": 80}, map[string]any{"host": "b", "port": 81}},
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target any
+		patch  any
+		want   any
+	}{
+		{
+			name:   "MergeNested",
+			target: testPatchDocument(),
+			patch:  map[string]any{"name": "gateway", "labels": map[string]any{"team": nil, "owner": "ops"}},
+			want: map[string]any{
+				"name":    "gateway",
+				"labels":  map[string]any{"tier": "web", "owner": "ops"},
+				"servers": []any{map[string]any{"host": "a", "port": 80}, map[string]any{"host": "b", "port": 81}},
+			},
+		},
+		{
+			name:   "ReplaceSlice",
+			target: map[string]any{"tags": []any{"a", "b"}},
+			patch:  map[string]any{"tags": []any{"c"}},
+			want:   map[string]any{"tags": []any{"c"}},
+		},
+		{name: "ReplaceScalarTarget", target: "text", patch: map[string]any{"a": map[string]any{"b": nil, "c": 1}}, want: map[string]any{"a": map[string]any{"c": 1}}},
+		{name: "ReplaceWithScalar", target: map[string]any{"a": 1}, patch: []any{1, 2}, want: []any{1, 2}},
+		{name: "NullPatch", target: map[string]any{"a": 1}, patch: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyMergePatch(tt.target, tt.patch)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []PatchOp
+		want func(doc map[string]any)
+	}{
+		{
+			name: "Add",
+			ops: []PatchOp{
+				{Op: "add", Path: "/labels/owner", Value: "ops"},
+				{Op: "add", Path: "/servers/1", Value: map[string]any{"host": "c", "port": 82}},
+				{Op: "add", Path: "/servers/-", Value: map[string]any{"host": "d", "port": 83}},
+			},
+			want: func(doc map[string]any) {
+				doc["labels"].(map[string]any)["owner"] = "ops"
+				doc["servers"] = []any{
+					map[string]any{"host": "a", "port": 80},
+					map[string]any{"host": "c", "port": 82},
+					map[string]any{"host": "b", "port": 81},
+					map[string]any{"host": "d", "port": 83},
+				}
+			},
+		},
+		{
+			name: "RemoveAndReplace",
+			ops: []PatchOp{
+				{Op: "remove", Path: "/servers/0"},
+				{Op: "replace", Path: "/servers/0/port", Value: 8080},
+				{Op: "replace", Path: "/labels/team", Value: nil},
+			},
+			want: func(doc map[string]any) {
+				doc["labels"].(map[string]any)["team"] = nil
+				doc["servers"] = []any{map[string]any{"host": "b", "port": 8080}}
+			},
+		},
+		{
+			name: "MoveAndCopy",
+			ops: []PatchOp{
+				{Op: "move", From: "/labels/tier", Path: "/tier"},
+				{Op: "copy", From: "/servers/0", Path: "/primary"},
+				{Op: "replace", Path: "/primary/host", Value: "z"},
+			},
+			want: func(doc map[string]any) {
+				delete(doc["labels"].(map[string]any), "tier")
+				doc["tier"] = "web"
+				doc["primary"] = map[string]any{"host": "z", "port": 80}
+			},
+		},
+		{
+			name: "Test",
+			ops: []PatchOp{
+				{Op: "test", Path: "/servers/1/port", Value: 81.0},
+				{Op: "test", Path: "/labels", Value: map[string]any{"team": "core", "tier": "web"}},
+			},
+			want: func(map[string]any) {},
+		},
+		{
+			name: "EscapedPointer",
+			ops:  []PatchOp{{Op: "add", Path: "/labels/app.io~1name", Value: "api"}, {Op: "add", Path: "/a~0b", Value: 1}},
+			want: func(doc map[string]any) {
+				doc["labels"].(map[string]any)["app.io/name"] = "api"
+				doc["a~b"] = 1
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := testPatchDocument()
+			got, err := ApplyPatch(doc, tt.ops)
+			require.NoError(t, err)
+			assert.Equal(t, testPatchDocument(), doc, "the input document must not be modified")
+
+			want := testPatchDocument()
+			tt.want(want)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestApplyPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     []PatchOp
+		wantErr string
+	}{
+		{
+			name:    "MissingKey",
+			ops:     []PatchOp{{Op: "add", Path: "/name", Value: "x"}, {Op: "replace", Path: "/owner", Value: "x"}},
+			wantErr: "patch operation 1 (replace /owner): path not found",
+		},
+		{
+			name:    "IndexOutOfRange",
+			ops:     []PatchOp{{Op: "remove", Path: "/servers/2"}},
+			wantErr: "patch operation 0 (remove /servers/2): path not found: index 2 out of range",
+		},
+		{
+			name:    "InvalidIndex",
+			ops:     []PatchOp{{Op: "add", Path: "/servers/01", Value: 1}},
+			wantErr: `patch operation 0 (add /servers/01): invalid array index "01"`,
+		},
+		{
+			name:    "MissingParent",
+			ops:     []PatchOp{{Op: "add", Path: "/owner/name", Value: "x"}},
+			wantErr: "patch operation 0 (add /owner/name): path not found",
+		},
+		{
+			name:    "TestFailed",
+			ops:     []PatchOp{{Op: "test", Path: "/name", Value: "gateway"}},
+			wantErr: `patch operation 0 (test /name): test failed: value is "api", expected "gateway"`,
+		},
+		{
+			name:    "MoveFromMissing",
+			ops:     []PatchOp{{Op: "move", From: "/owner", Path: "/name"}},
+			wantErr: "patch operation 0 (move /name): from /owner: path not found",
+		},
+		{
+			name:    "MoveIntoItself",
+			ops:     []PatchOp{{Op: "move", From: "/labels", Path: "/labels/nested"}},
+			wantErr: "patch operation 0 (move /labels/nested): cannot move /labels into itself",
+		},
+		{
+			name:    "InvalidPointer",
+			ops:     []PatchOp{{Op: "remove", Path: "name"}},
+			wantErr: `patch operation 0 (remove name): invalid JSON pointer "name": must start with /`,
+		},
+		{
+			name:    "UnknownOperation",
+			ops:     []PatchOp{{Op: "merge", Path: "/name"}},
+			wantErr: `patch operation 0 (merge /name): unknown operation "merge"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ApplyPatch(testPatchDocument(), tt.ops)
+			var patchErr *PatchError
+			require.ErrorAs(t, err, &patchErr)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestReadPatch(t *testing.T) {
+	ops, err := ReadPatch(strings.NewReader(`[
+		{"op": "replace", "path": "/port", "value": null},
+		{"op": "move", "from": "/a", "path": "/b"},
+		{"op": "remove", "path": "/c"}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, []PatchOp{
+		{Op: "replace", Path: "/port"},
+		{Op: "move", Path: "/b", From: "/a"},
+		{Op: "remove", Path: "/c"},
+	}, ops)
+
+	ops, err = ReadPatch(strings.NewReader("- op: add\n  path: /tags/-\n  value: {name: x}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []PatchOp{{Op: "add", Path: "/tags/-", Value: map[string]any{"name": "x"}}}, ops)
+
+	_, err = ReadPatch(strings.NewReader(`[{"op": "add", "path": "/a"}]`))
+	assert.EqualError(t, err, `patch operation 0 (add /a): missing "value"`)
+	_, err = ReadPatch(strings.NewReader(`[{"op": "copy", "path": "/a"}]`))
+	assert.EqualError(t, err, `patch operation 0 (copy /a): missing "from"`)
+}
+
+func TestWithPatch(t *testing.T) {
+	type server struct {
+		Host string `yaml:"host" json:"host"`
+		Port int    `yaml:"port" json:"port"`
+	}
+	type config struct {
+		Name    string   `yaml:"name" json:"name"`
+		Servers []server `yaml:"servers" json:"servers"`
+	}
+	input := "name: api\nservers:\n  - host: a\n    port: 80\n"
+
+	got, err := FromYAML[config](strings.NewReader(input),
+		WithMergePatch(map[string]any{"name": "gateway"}),
+		WithPatch(PatchOp{Op: "add", Path: "/servers/-", Value: server{Host: "b", Port: 81}}))
+	require.NoError(t, err)
+	assert.Equal(t, config{Name: "gateway", Servers: []server{{Host: "a", Port: 80}, {Host: "b", Port: 81}}}, got)
+
+	got, err = FromJSON[config](strings.NewReader(`{"name": "api", "servers": []}`),
+		WithPatch(PatchOp{Op: "add", Path: "/servers/0", Value: map[string]any{"host": "c", "port": 82}}))
+	require.NoError(t, err)
+	assert.Equal(t, []server{{Host: "c", Port: 82}}, got.Servers)
+
+	_, err = FromYAML[config](strings.NewReader(input), WithPatch(PatchOp{Op: "remove", Path: "/owner"}))
+	assert.EqualError(t, err, "patch operation 0 (remove /owner): path not found")
+}
+
+func TestApplyPatch_FromDiff(t *testing.T) {
+	a, b := testDiffConfigs()
+	changes, err := Diff(a, b)
+	require.NoError(t, err)
+
+	got, err := ApplyPatch(a, changes.JSONPatch())
+	require.NoError(t, err)
+	want, err := genericValue(b)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
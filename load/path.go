@@ -0,0 +1,115 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrPathNotFound is returned when a key path does not address any value
+// of a document.
+var ErrPathNotFound = errors.New("path not found")
+
+// pathSegment is one step of a key path: a mapping key or a sequence
+// index.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath splits a key path into its segments. Paths use dots between
+// keys and brackets around indexes, e.g. "servers[0].port", and may be
+// written JSONPath-style with a leading "$" and quoted keys in brackets,
+// e.g. `$.labels["app.kubernetes.io/name"]`. An empty path or "$" alone
+// addresses the document root.
+func parsePath(path string) ([]pathSegment, error) {
+	s := strings.TrimPrefix(path, "$")
+	var segments []pathSegment
+	for i := 0; i < len(s); {
+		if s[i] == '[' {
+			segment, n, err := bracketSegment(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", path, err)
+			}
+			segments = append(segments, segment)
+			i += n
+			continue
+		}
+		if s[i] == '.' {
+			i++
+		} else if i > 0 {
+			return nil, fmt.Errorf("invalid path %q: expected . or [ at offset %d", path, i)
+		}
+		end := strings.IndexAny(s[i:], ".[")
+		if end < 0 {
+			end = len(s) - i
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("invalid path %q: empty key at offset %d", path, i)
+		}
+		segments = append(segments, pathSegment{key: s[i : i+end]})
+		i += end
+	}
+	return segments, nil
+}
+
+// bracketSegment reads an index or a quoted key in brackets at the start
+// of s and returns it along with the number of bytes it spans.
+func bracketSegment(s string) (pathSegment, int, error) {
+	if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+		key, n, err := quotedKey(s[1:])
+		if err != nil {
+			return pathSegment{}, 0, err
+		}
+		if !strings.HasPrefix(s[1+n:], "]") {
+			return pathSegment{}, 0, errors.New("expected ] after quoted key")
+		}
+		return pathSegment{key: key}, n + 2, nil
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathSegment{}, 0, errors.New("unterminated bracket")
+	}
+	index, err := strconv.Atoi(s[1:end])
+	if err != nil || index < 0 {
+		return pathSegment{}, 0, fmt.Errorf("bad index %q", s[1:end])
+	}
+	return pathSegment{index: index, isIndex: true}, end + 1, nil
+}
+
+// quotedKey reads a single- or double-quoted key at the start of s and
+// returns it along with the number of bytes it spans.
+func quotedKey(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, errors.New("unterminated escape")
+			}
+			i++
+			b.WriteByte(s[i])
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, errors.New("unterminated quoted key")
+}
+
+// formatPath renders segments back into a dotted path for messages.
+func formatPath(segments []pathSegment) string {
+	var path string
+	for _, s := range segments {
+		if s.isIndex {
+			path = indexPath(path, s.index)
+		} else {
+			path = joinPath(path, s.key)
+		}
+	}
+	return path
+}
@@ -0,0 +1,52 @@
+package load
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []pathSegment
+	}{
+		{path: "", want: nil},
+		{path: "$", want: nil},
+		{path: "name", want: []pathSegment{{key: "name"}}},
+		{path: "servers[2].port", want: []pathSegment{{key: "servers"}, {index: 2, isIndex: true}, {key: "port"}}},
+		{path: "$.servers[0]", want: []pathSegment{{key: "servers"}, {index: 0, isIndex: true}}},
+		{path: "[1][0]", want: []pathSegment{{index: 1, isIndex: true}, {index: 0, isIndex: true}}},
+		{path: `labels["app.io/name"]`, want: []pathSegment{{key: "labels"}, {key: "app.io/name"}}},
+		{path: `$['it\'s']`, want: []pathSegment{{key: "it's"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{path: "a..b", wantErr: "empty key"},
+		{path: "a.", wantErr: "empty key"},
+		{path: "a[", wantErr: "unterminated bracket"},
+		{path: "a[-1]", wantErr: "bad index"},
+		{path: "a[x]", wantErr: "bad index"},
+		{path: `a["b]`, wantErr: "unterminated quoted key"},
+		{path: "a[0]b", wantErr: "expected . or ["},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := parsePath(tt.path)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
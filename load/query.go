@@ -0,0 +1,710 @@
+package load
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Get returns the value at a key path of a generic document, such as the
+// result of FromJSON[map[string]any], converted to T. Paths use the syntax
+// of YAMLDocument, e.g. "servers[0].port". Values that are not already a
+// T are converted the way FromYAML would decode them, so a JSON number
+// reads as an int and a nested map reads as a struct. It fails with
+// ErrPathNotFound when the path does not exist.
+func Get[T any](doc any, path string) (T, error) {
+	var v T
+	segments, err := parsePath(path)
+	if err != nil {
+		return v, err
+	}
+	value := doc
+	for i, s := range segments {
+		var ok bool
+		if value, ok = childValue(value, s); !ok {
+			return v, fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(segments[:i+1]))
+		}
+	}
+	if typed, ok := value.(T); ok {
+		return typed, nil
+	}
+	e := &encoder{format: FormatYAML, tag: "yaml"}
+	node, err := e.node(reflect.ValueOf(value))
+	if err != nil {
+		return v, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	if err := node.Decode(&v); err != nil {
+		var zero T
+		return zero, fmt.Errorf("cannot read %s as %T: %w", path, v, err)
+	}
+	return v, nil
+}
+
+// childValue returns the child of a generic value addressed by a path
+// segment.
+func childValue(v any, s pathSegment) (any, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch {
+	case s.isIndex && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array):
+		if s.index < rv.Len() {
+			return rv.Index(s.index).Interface(), true
+		}
+	case !s.isIndex && rv.Kind() == reflect.Map:
+		key := reflect.ValueOf(s.key)
+		switch rv.Type().Key().Kind() {
+		case reflect.String:
+			key = key.Convert(rv.Type().Key())
+		case reflect.Interface:
+		default:
+			return nil, false
+		}
+		if child := rv.MapIndex(key); child.IsValid() {
+			return child.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// Query is a compiled query over generic documents, written in a subset
+// of the jq language:
+//
+//   - `.` is the input itself
+//   - `.name`, `."name"` and `.["name"]` read a key, or null when it is
+//     missing
+//   - `.[0]` reads an item of a sequence, counting from the end when it
+//     is negative
+//   - `.[]` yields every item of a sequence or value of a mapping
+//   - a `?` after any of the above skips values that cannot be indexed
+//   - `a | b` runs b on every result of a
+//   - `select(cond)` keeps the inputs for which cond is true
+//   - `==`, `!=`, `<`, `<=`, `>`, `>=`, `and`, `or` and `not` compare and
+//     combine values
+//   - `length` and `keys` return the size and the sorted keys of a value
+//   - strings, numbers, true, false and null are literals
+//
+// For example `.servers[] | select(.enabled) | .name` yields the names of
+// the enabled servers.
+type Query struct {
+	expr string
+	run  queryFunc
+}
+
+// queryFunc maps an input value to the values a query yields for it.
+type queryFunc func(v any) ([]any, error)
+
+// ParseQuery compiles a query.
+func ParseQuery(expr string) (*Query, error) {
+	p := &queryParser{src: expr}
+	run, err := p.pipeline()
+	if err == nil && !p.done() {
+		err = p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", expr, err)
+	}
+	return &Query{expr: expr, run: run}, nil
+}
+
+// RunQuery compiles a query and runs it over a generic document.
+func RunQuery(doc any, expr string) ([]any, error) {
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Run(doc)
+}
+
+// Run returns the values the query yields for a generic document.
+func (q *Query) Run(doc any) ([]any, error) {
+	results, err := q.run(doc)
+	if err != nil {
+		return nil, fmt.Errorf("query %q: %w", q.expr, err)
+	}
+	return results, nil
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+// queryParser compiles queries by recursive descent. From the lowest
+// precedence up, a query is made of pipes, "or", "and", comparisons and
+// terms.
+type queryParser struct {
+	src string
+	pos int
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *queryParser) done() bool {
+	p.skipSpace()
+	return p.pos >= len(p.src)
+}
+
+// accept consumes the given token when it comes next. Word tokens must not
+// be followed by an identifier character.
+func (p *queryParser) accept(token string) bool {
+	p.skipSpace()
+	if !strings.HasPrefix(p.src[p.pos:], token) {
+		return false
+	}
+	end := p.pos + len(token)
+	if isIdentByte(token[len(token)-1]) && end < len(p.src) && isIdentByte(p.src[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *queryParser) expect(token string) error {
+	if !p.accept(token) {
+		return p.errorf("expected %q", token)
+	}
+	return nil
+}
+
+func (p *queryParser) pipeline() (queryFunc, error) {
+	left, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		right, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		left = pipe(left, right)
+	}
+	return left, nil
+}
+
+func (p *queryParser) or() (queryFunc, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = combine(left, right, func(a, b any) (any, error) {
+			return truthy(a) || truthy(b), nil
+		})
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (queryFunc, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = combine(left, right, func(a, b any) (any, error) {
+			return truthy(a) && truthy(b), nil
+		})
+	}
+	return left, nil
+}
+
+func (p *queryParser) comparison() (queryFunc, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			return combine(left, right, func(a, b any) (any, error) {
+				result, err := compareValues(op, a, b)
+				return result, err
+			}), nil
+		}
+	}
+	return left, nil
+}
+
+func (p *queryParser) term() (queryFunc, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of query")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '.':
+		return p.path()
+	case c == '"':
+		s, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return constant(s), nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.numberLiteral()
+	case c == '(':
+		p.pos++
+		inner, err := p.pipeline()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return p.suffixes(inner)
+	}
+
+	switch word := p.identifier(); word {
+	case "true":
+		return constant(true), nil
+	case "false":
+		return constant(false), nil
+	case "null":
+		return constant(nil), nil
+	case "not":
+		return func(v any) ([]any, error) { return []any{!truthy(v)}, nil }, nil
+	case "length":
+		return func(v any) ([]any, error) {
+			n, err := valueLength(v)
+			return []any{n}, err
+		}, nil
+	case "keys":
+		return func(v any) ([]any, error) {
+			keys, err := valueKeys(v)
+			return []any{keys}, err
+		}, nil
+	case "select":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond, err := p.pipeline()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return selectFunc(cond), nil
+	case "":
+		return nil, p.errorf("unexpected %q", p.src[p.pos:p.pos+1])
+	default:
+		return nil, p.errorf("unknown function %q", word)
+	}
+}
+
+// path parses a path starting with a dot.
+func (p *queryParser) path() (queryFunc, error) {
+	p.pos++ // leading dot
+	var f queryFunc = func(v any) ([]any, error) { return []any{v}, nil }
+	if p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '"':
+			key, err := p.stringLiteral()
+			if err != nil {
+				return nil, err
+			}
+			f = p.optional(fieldFunc(key))
+		case isIdentByte(c):
+			f = p.optional(fieldFunc(p.identifier()))
+		}
+	}
+	return p.suffixes(f)
+}
+
+// suffixes parses the keys, indexes and iterations that follow a term.
+func (p *queryParser) suffixes(f queryFunc) (queryFunc, error) {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '.':
+			if p.pos+1 >= len(p.src) {
+				return nil, p.errorf("expected a key after \".\"")
+			}
+			p.pos++
+			var key string
+			if p.src[p.pos] == '"' {
+				var err error
+				if key, err = p.stringLiteral(); err != nil {
+					return nil, err
+				}
+			} else if key = p.identifier(); key == "" {
+				return nil, p.errorf("expected a key after \".\"")
+			}
+			f = pipe(f, p.optional(fieldFunc(key)))
+		case '[':
+			p.pos++
+			next, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			f = pipe(f, p.optional(next))
+		default:
+			return f, nil
+		}
+	}
+	return f, nil
+}
+
+// bracket parses the inside of a bracket suffix.
+func (p *queryParser) bracket() (queryFunc, error) {
+	if p.accept("]") {
+		return iterateFunc, nil
+	}
+	p.skipSpace()
+	var f queryFunc
+	switch {
+	case p.pos < len(p.src) && p.src[p.pos] == '"':
+		key, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		f = fieldFunc(key)
+	default:
+		start := p.pos
+		if p.pos < len(p.src) && p.src[p.pos] == '-' {
+			p.pos++
+		}
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+		n, err := strconv.Atoi(p.src[start:p.pos])
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("expected an index, a quoted key or \"]\"")
+		}
+		f = indexFunc(n)
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// optional makes a path step skip the values it cannot index when it is
+// followed by "?".
+func (p *queryParser) optional(f queryFunc) queryFunc {
+	if p.pos >= len(p.src) || p.src[p.pos] != '?' {
+		return f
+	}
+	p.pos++
+	return func(v any) ([]any, error) {
+		results, err := f(v)
+		if err != nil {
+			return nil, nil
+		}
+		return results, nil
+	}
+}
+
+func (p *queryParser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.src) && isIdentByte(p.src[p.pos]) && (p.pos > start || !unicode.IsDigit(rune(p.src[p.pos]))) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *queryParser) stringLiteral() (string, error) {
+	start := p.pos
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '"':
+			s, err := strconv.Unquote(p.src[start : i+1])
+			if err != nil {
+				return "", p.errorf("invalid string %s", p.src[start:i+1])
+			}
+			p.pos = i + 1
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *queryParser) numberLiteral() (queryFunc, error) {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid number")
+	}
+	return constant(n), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// constant yields a literal value.
+func constant(value any) queryFunc {
+	return func(any) ([]any, error) {
+		return []any{value}, nil
+	}
+}
+
+// pipe runs right on every result of left.
+func pipe(left, right queryFunc) queryFunc {
+	return func(v any) ([]any, error) {
+		inputs, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		var results []any
+		for _, input := range inputs {
+			out, err := right(input)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, out...)
+		}
+		return results, nil
+	}
+}
+
+// combine applies a binary operator to every pair of results of two
+// queries run on the same input.
+func combine(left, right queryFunc, op func(a, b any) (any, error)) queryFunc {
+	return func(v any) ([]any, error) {
+		as, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		bs, err := right(v)
+		if err != nil {
+			return nil, err
+		}
+		var results []any
+		for _, a := range as {
+			for _, b := range bs {
+				result, err := op(a, b)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, result)
+			}
+		}
+		return results, nil
+	}
+}
+
+// selectFunc yields the input once for every true result of cond.
+func selectFunc(cond queryFunc) queryFunc {
+	return func(v any) ([]any, error) {
+		results, err := cond(v)
+		if err != nil {
+			return nil, err
+		}
+		var selected []any
+		for _, result := range results {
+			if truthy(result) {
+				selected = append(selected, v)
+			}
+		}
+		return selected, nil
+	}
+}
+
+// fieldFunc reads a key of a mapping.
+func fieldFunc(key string) queryFunc {
+	return func(v any) ([]any, error) {
+		if v == nil {
+			return []any{nil}, nil
+		}
+		if child, ok := childValue(v, pathSegment{key: key}); ok {
+			return []any{child}, nil
+		}
+		if reflect.Indirect(reflect.ValueOf(v)).Kind() != reflect.Map {
+			return nil, fmt.Errorf("cannot read key %q of %s", key, queryType(v))
+		}
+		return []any{nil}, nil
+	}
+}
+
+// indexFunc reads an item of a sequence.
+func indexFunc(i int) queryFunc {
+	return func(v any) ([]any, error) {
+		if v == nil {
+			return []any{nil}, nil
+		}
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("cannot read index %d of %s", i, queryType(v))
+		}
+		j := i
+		if j < 0 {
+			j += rv.Len()
+		}
+		if j < 0 || j >= rv.Len() {
+			return []any{nil}, nil
+		}
+		return []any{rv.Index(j).Interface()}, nil
+	}
+}
+
+// iterateFunc yields the items of a sequence or the values of a mapping,
+// ordered by key.
+func iterateFunc(v any) ([]any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		results := make([]any, rv.Len())
+		for i := range results {
+			results[i] = rv.Index(i).Interface()
+		}
+		return results, nil
+	case reflect.Map:
+		keys := rv.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		results := make([]any, len(keys))
+		for i, key := range keys {
+			results[i] = rv.MapIndex(key).Interface()
+		}
+		return results, nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", queryType(v))
+	}
+}
+
+// valueLength returns the number of items, entries or characters of a
+// value, the absolute value of a number, or 0 for null.
+func valueLength(v any) (any, error) {
+	if v == nil {
+		return 0, nil
+	}
+	if n, ok := jsonNumber(v); ok {
+		return max(n, -n), nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.String:
+		return len([]rune(rv.String())), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len(), nil
+	default:
+		return nil, fmt.Errorf("%s has no length", queryType(v))
+	}
+}
+
+// valueKeys returns the sorted keys of a mapping or the indexes of a
+// sequence.
+func valueKeys(v any) (any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := make([]any, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+		}
+		slices.SortFunc(keys, func(a, b any) int { return cmp.Compare(a.(string), b.(string)) })
+		return keys, nil
+	case reflect.Slice, reflect.Array:
+		keys := make([]any, rv.Len())
+		for i := range keys {
+			keys[i] = i
+		}
+		return keys, nil
+	default:
+		return nil, fmt.Errorf("%s has no keys", queryType(v))
+	}
+}
+
+// compareValues applies a comparison operator. Numbers compare by value
+// whatever their Go type, and strings compare lexically.
+func compareValues(op string, a, b any) (bool, error) {
+	switch op {
+	case "==":
+		return jsonEqual(a, b), nil
+	case "!=":
+		return !jsonEqual(a, b), nil
+	}
+	var c int
+	x, xNum := jsonNumber(a)
+	y, yNum := jsonNumber(b)
+	s, sStr := a.(string)
+	t, tStr := b.(string)
+	switch {
+	case xNum && yNum:
+		c = cmp.Compare(x, y)
+	case sStr && tStr:
+		c = strings.Compare(s, t)
+	default:
+		return false, fmt.Errorf("cannot compare %s with %s", queryType(a), queryType(b))
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// truthy reports whether a value counts as true: anything but false and
+// null.
+func truthy(v any) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+// queryType names the kind of a value in query errors.
+func queryType(v any) string {
+	if v == nil {
+		return "null"
+	}
+	if _, ok := jsonNumber(v); ok {
+		return "number"
+	}
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,167 @@
+package load
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testQueryJSON = `{
+	"name": "api",
+	"timeout": "1m30s",
+	"labels": {"tier": "web", "app.io/name": "api"},
+	"servers": [
+		{"name": "a", "port": 80, "enabled": true},
+		{"name": "b", "port": 81, "enabled": false},
+		{"name": "c", "port": 8080, "enabled": true}
+	]
+}`
+
+func testQueryDocument(t *testing.T) map[string]any {
+	t.Helper()
+	doc, err := FromJSON[map[string]any](strings.NewReader(testQueryJSON))
+	require.NoError(t, err)
+	return doc
+}
+
+func TestGet(t *testing.T) {
+	doc := testQueryDocument(t)
+
+	port, err := Get[int](doc, "servers[2].port")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, port)
+
+	name, err := Get[string](doc, `labels["app.io/name"]`)
+	require.NoError(t, err)
+	assert.Equal(t, "api", name)
+
+	timeout, err := Get[time.Duration](doc, "timeout")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+
+	server, err := Get[testDiffServer](doc, "$.servers[0]")
+	require.NoError(t, err)
+	assert.Equal(t, testDiffServer{Name: "a", Port: 80, Enabled: true}, server)
+
+	labels, err := Get[map[string]string](doc, "labels")
+	require.NoError(t, err)
+	assert.Equal(t, "web", labels["tier"])
+
+	ports, err := Get[[]int](map[string][]int{"ports": {80, 81}}, "ports")
+	require.NoError(t, err)
+	assert.Equal(t, []int{80, 81}, ports)
+}
+
+func TestGet_Errors(t *testing.T) {
+	doc := testQueryDocument(t)
+
+	_, err := Get[int](doc, "servers[5].port")
+	assert.ErrorIs(t, err, ErrPathNotFound)
+	assert.EqualError(t, err, "path not found: servers[5]")
+
+	_, err = Get[int](doc, "name.first")
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	_, err = Get[int](doc, "name")
+	assert.ErrorContains(t, err, "cannot read name as int")
+
+	_, err = Get[int](doc, "servers[")
+	assert.ErrorContains(t, err, "invalid path")
+}
+
+func TestRunQuery(t *testing.T) {
+	doc := testQueryDocument(t)
+
+	tests := []struct {
+		query string
+		want  []any
+	}{
+		{query: ".name", want: []any{"api"}},
+		{query: ".", want: []any{doc}},
+		{query: ".servers[] | select(.enabled) | .name", want: []any{"a", "c"}},
+		{query: ".servers[].port", want: []any{80.0, 81.0, 8080.0}},
+		{query: ".servers[-1].name", want: []any{"c"}},
+		{query: `.servers[] | select(.port >= 81 and .name != "c") | .name`, want: []any{"b"}},
+		{query: `.servers[] | select(.enabled | not) | .name`, want: []any{"b"}},
+		{query: `.servers[] | select(.name == "a" or .port > 1000) | .port`, want: []any{80.0, 8080.0}},
+		{query: `.labels["app.io/name"]`, want: []any{"api"}},
+		{query: `.labels."app.io/name"`, want: []any{"api"}},
+		{query: ".labels[]", want: []any{"api", "web"}},
+		{query: ".labels | keys", want: []any{[]any{"app.io/name", "tier"}}},
+		{query: ".servers | length", want: []any{3}},
+		{query: ".missing", want: []any{nil}},
+		{query: ".missing.deeper[0]", want: []any{nil}},
+		{query: ".name[]?", want: nil},
+		{query: ".servers[0].port.x?", want: nil},
+		{query: "(.servers[0]).name", want: []any{"a"}},
+		{query: `"literal"`, want: []any{"literal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := RunQuery(doc, tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRunQuery_NegativeIndex(t *testing.T) {
+	doc := map[string]any{"lists": []any{[]any{1, 2, 3}, []any{4, 5, 6, 7, 8}, []any{9}}}
+
+	got, err := RunQuery(doc, ".lists[] | .[-1]")
+	require.NoError(t, err)
+	assert.Equal(t, []any{3, 8, 9}, got)
+
+	q, err := ParseQuery(".[-1]")
+	require.NoError(t, err)
+	for _, list := range doc["lists"].([]any) {
+		got, err := q.Run(list)
+		require.NoError(t, err)
+		assert.Equal(t, []any{list.([]any)[len(list.([]any))-1]}, got)
+	}
+}
+
+func TestRunQuery_Errors(t *testing.T) {
+	doc := testQueryDocument(t)
+
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{query: "", wantErr: `invalid query "": offset 0: unexpected end of query`},
+		{query: ".servers[", wantErr: `invalid query ".servers[": offset 9: expected an index, a quoted key or "]"`},
+		{query: ".name |", wantErr: `invalid query ".name |": offset 7: unexpected end of query`},
+		{query: "select(.a", wantErr: `invalid query "select(.a": offset 9: expected ")"`},
+		{query: "map(.a)", wantErr: `invalid query "map(.a)": offset 3: unknown function "map"`},
+		{query: `.a "b"`, wantErr: `invalid query ".a \"b\"": offset 3: unexpected "\"b\""`},
+		{query: ".name[]", wantErr: `query ".name[]": cannot iterate over string`},
+		{query: ".servers.name", wantErr: `query ".servers.name": cannot read key "name" of array`},
+		{query: ".name[0]", wantErr: `query ".name[0]": cannot read index 0 of string`},
+		{query: ".servers[] | select(.port < .name)", wantErr: `query ".servers[] | select(.port < .name)": cannot compare number with string`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := RunQuery(doc, tt.query)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseQuery_Reuse(t *testing.T) {
+	q, err := ParseQuery(".servers[] | select(.enabled) | .port")
+	require.NoError(t, err)
+	assert.Equal(t, ".servers[] | select(.enabled) | .port", q.String())
+
+	yamlDoc, err := FromYAML[map[string]any](strings.NewReader("servers:\n  - {port: 80, enabled: true}\n  - {port: 81}\n"))
+	require.NoError(t, err)
+	got, err := q.Run(yamlDoc)
+	require.NoError(t, err)
+	assert.Equal(t, []any{80}, got)
+
+	got, err = q.Run(testQueryDocument(t))
+	require.NoError(t, err)
+	assert.Equal(t, []any{80.0, 8080.0}, got)
+}
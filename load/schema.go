@@ -0,0 +1,277 @@
+package load
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaDialect is the JSON Schema draft that generated schemas declare.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// Schema reflects over T and returns a JSON Schema (draft 2020-12)
+// document describing it as it appears in JSON documents. It is
+// SchemaFor with FormatJSON.
+func Schema[T any](opts ...Option) ([]byte, error) {
+	return SchemaFor[T](FormatJSON, opts...)
+}
+
+// SchemaFor reflects over T and returns a JSON Schema (draft 2020-12)
+// document describing it as it appears in documents of the given format.
+// Property names follow the format's struct tags, and the `validate`,
+// `default` and `description` tags become constraints, defaults and
+// descriptions. Named struct types are emitted under "$defs" so that
+// recursive types are supported. Objects only reject unknown properties
+// when the Strict option is given, matching how documents are loaded.
+func SchemaFor[T any](format Format, opts ...Option) ([]byte, error) {
+	c, err := codecFor(format)
+	if err != nil {
+		return nil, err
+	}
+	b := &schemaBuilder{
+		format: format,
+		tag:    c.tag,
+		strict: newOptions(opts).strict,
+		defs:   map[string]any{},
+		names:  map[reflect.Type]string{},
+	}
+
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var root map[string]any
+	if t.Kind() == reflect.Struct && !hasCustomUnmarshaler(t) {
+		b.names[t] = "#"
+		root = b.structSchema(t)
+	} else {
+		root = b.typeSchema(t)
+	}
+	root["$schema"] = SchemaDialect
+	if t.Name() != "" {
+		root["title"] = t.Name()
+	}
+	if len(b.defs) > 0 {
+		root["$defs"] = b.defs
+	}
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// schemaBuilder accumulates the definitions of a schema being generated.
+type schemaBuilder struct {
+	format Format
+	tag    string
+	strict bool
+	defs   map[string]any
+	names  map[reflect.Type]string
+}
+
+// typeSchema returns the schema of a Go type.
+func (b *schemaBuilder) typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		if b.format != FormatYAML {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "string", "pattern": `^([-+]?([0-9]*(\.[0-9]*)?[a-zµ]+)+|0)$`}
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+		s := map[string]any{"type": "array", "items": b.typeSchema(t.Elem())}
+		if t.Kind() == reflect.Array {
+			s["minItems"], s["maxItems"] = t.Len(), t.Len()
+		}
+		return s
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if hasCustomUnmarshaler(t) {
+			return map[string]any{}
+		}
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return map[string]any{"$ref": b.define(t)}
+	default:
+		return map[string]any{}
+	}
+}
+
+// define registers a named struct type under "$defs" and returns its
+// reference.
+func (b *schemaBuilder) define(t reflect.Type) string {
+	if ref, ok := b.names[t]; ok {
+		return ref
+	}
+	name := t.Name()
+	if _, taken := b.defs[name]; taken {
+		name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + name
+	}
+	ref := "#/$defs/" + name
+	b.names[t] = ref
+	b.defs[name] = map[string]any{}
+	b.defs[name] = b.structSchema(t)
+	return ref
+}
+
+// structSchema returns the object schema of a struct type.
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	var additional any
+	if b.strict {
+		additional = false
+	}
+	for _, f := range structFields(t, b.tag) {
+		if f.inline {
+			additional = b.typeSchema(f.field.Type.Elem())
+			continue
+		}
+		// Draft 2020-12 allows keywords next to $ref, so constraints can be
+		// added to references directly.
+		prop := b.typeSchema(f.field.Type)
+		if desc := f.field.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+		if def, ok := f.field.Tag.Lookup("default"); ok {
+			if value, err := b.defaultValue(f.field.Type, def); err == nil {
+				prop["default"] = value
+			}
+		}
+		if b.applyRules(prop, f.field.Type, f.field.Tag.Get("validate")) {
+			required = append(required, f.key)
+		}
+		properties[f.key] = prop
+	}
+
+	s := map[string]any{"type": "object", "properties": properties}
+	if additional != nil {
+		s["additionalProperties"] = additional
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// applyRules translates validate tag rules into schema keywords and
+// reports whether the field is required.
+func (b *schemaBuilder) applyRules(prop map[string]any, t reflect.Type, rules string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	required := false
+	for _, rule := range splitRules(rules) {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			keyword, value, ok := boundKeyword(t, name, arg, b.format)
+			if ok {
+				prop[keyword] = value
+			}
+		case "oneof":
+			var enum []any
+			for _, option := range strings.Fields(arg) {
+				if value, err := b.defaultValue(t, option); err == nil {
+					enum = append(enum, value)
+				}
+			}
+			prop["enum"] = enum
+		case "regex":
+			prop["pattern"] = arg
+		}
+	}
+	return required
+}
+
+// boundKeyword maps a min or max rule onto the matching schema keyword.
+func boundKeyword(t reflect.Type, rule string, arg string, format Format) (string, any, bool) {
+	lower := rule == "min"
+	pick := func(minimum, maximum string) string {
+		if lower {
+			return minimum
+		}
+		return maximum
+	}
+
+	if t == durationType {
+		if format == FormatYAML {
+			return "", nil, false
+		}
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return "", nil, false
+		}
+		return pick("minimum", "maximum"), int64(d), true
+	}
+
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return "", nil, false
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return pick("minLength", "maxLength"), int(n), true
+	case reflect.Slice, reflect.Array:
+		return pick("minItems", "maxItems"), int(n), true
+	case reflect.Map:
+		return pick("minProperties", "maxProperties"), int(n), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if n == float64(int64(n)) {
+			return pick("minimum", "maximum"), int64(n), true
+		}
+		return pick("minimum", "maximum"), n, true
+	}
+	return "", nil, false
+}
+
+// defaultValue parses a textual default into the JSON value it stands for.
+func (b *schemaBuilder) defaultValue(t reflect.Type, text string) (any, error) {
+	if t == durationType && b.format == FormatYAML {
+		return text, nil
+	}
+	v := reflect.New(t).Elem()
+	if err := setFromString(v, text); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("invalid default %q: %w", text, err)
+	}
+	return value, nil
+}
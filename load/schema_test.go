@@ -0,0 +1,154 @@
+package load
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSchemaServer struct {
+	Host    string        `json:"host" yaml:"host" validate:"required,regex=^[a-z.]+$" description:"Hostname of the server"`
+	Port    int           `json:"port" yaml:"port" default:"8080" validate:"min=1,max=65535"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout" default:"5s"`
+}
+
+type testSchemaNode struct {
+	Name     string            `json:"name" yaml:"name"`
+	Children []*testSchemaNode `json:"children,omitempty" yaml:"children"`
+}
+
+type testSchemaConfig struct {
+	Name      string                      `json:"name" yaml:"name" validate:"required,min=3"`
+	Mode      string                      `json:"mode" yaml:"mode" validate:"oneof=fast safe" default:"safe"`
+	Level     int                         `json:"level" yaml:"log_level" validate:"oneof=1 2 3"`
+	Ratio     float64                     `json:"ratio" yaml:"ratio"`
+	Enabled   bool                        `json:"enabled" yaml:"enabled"`
+	Started   time.Time                   `json:"started" yaml:"started"`
+	Tags      []string                    `json:"tags" yaml:"tags" validate:"min=1"`
+	Labels    map[string]string           `json:"labels" yaml:"labels"`
+	Primary   testSchemaServer            `json:"primary" yaml:"primary"`
+	Replicas  []testSchemaServer          `json:"replicas" yaml:"replicas"`
+	Tree      *testSchemaNode             `json:"tree" yaml:"tree"`
+	Inline    struct{ A string }          `json:"inline" yaml:"inline"`
+	Extra     any                         `json:"extra" yaml:"extra"`
+	Ignored   string                      `json:"-" yaml:"-"`
+	Zones     map[string]testSchemaServer `json:"zones" yaml:"zones"`
+	unexposed string
+}
+
+func generateSchema(t *testing.T, format Format, opts ...Option) map[string]any {
+	t.Helper()
+	data, err := SchemaFor[testSchemaConfig](format, opts...)
+	require.NoError(t, err)
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(data, &schema))
+	return schema
+}
+
+func TestSchema_YAML(t *testing.T) {
+	schema := generateSchema(t, FormatYAML, Strict())
+
+	assert.Equal(t, SchemaDialect, schema["$schema"])
+	assert.Equal(t, "testSchemaConfig", schema["title"])
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	assert.Equal(t, []any{"name"}, schema["required"])
+
+	props := schema["properties"].(map[string]any)
+	assert.NotContains(t, props, "Ignored")
+	assert.NotContains(t, props, "unexposed")
+	assert.Contains(t, props, "log_level")
+
+	assert.Equal(t, map[string]any{"type": "string", "minLength": float64(3)}, props["name"])
+	assert.Equal(t, map[string]any{"type": "string", "enum": []any{"fast", "safe"}, "default": "safe"}, props["mode"])
+	assert.Equal(t, map[string]any{"type": "integer", "enum": []any{float64(1), float64(2), float64(3)}}, props["log_level"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, props["started"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": float64(1)}, props["tags"])
+	assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}, props["labels"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/testSchemaServer"}, props["primary"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/testSchemaServer"}}, props["replicas"])
+	assert.Equal(t, map[string]any{}, props["extra"])
+
+	inline := props["inline"].(map[string]any)
+	assert.Equal(t, "object", inline["type"])
+	assert.Contains(t, inline["properties"], "a")
+
+	defs := schema["$defs"].(map[string]any)
+	server := defs["testSchemaServer"].(map[string]any)
+	serverProps := server["properties"].(map[string]any)
+	assert.Equal(t, []any{"host"}, server["required"])
+	assert.Equal(t, map[string]any{
+		"type":        "string",
+		"pattern":     "^[a-z.]+$",
+		"description": "Hostname of the server",
+	}, serverProps["host"])
+	assert.Equal(t, map[string]any{
+		"type":    "integer",
+		"default": float64(8080),
+		"minimum": float64(1),
+		"maximum": float64(65535),
+	}, serverProps["port"])
+	assert.Equal(t, "5s", serverProps["timeout"].(map[string]any)["default"])
+
+	node := defs["testSchemaNode"].(map[string]any)
+	children := node["properties"].(map[string]any)["children"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/testSchemaNode"}, children["items"])
+}
+
+func TestSchema_JSON(t *testing.T) {
+	schema := generateSchema(t, FormatJSON)
+
+	props := schema["properties"].(map[string]any)
+	assert.Contains(t, props, "level")
+	assert.NotContains(t, props, "log_level")
+	assert.NotContains(t, schema, "additionalProperties")
+
+	defs := schema["$defs"].(map[string]any)
+	timeout := defs["testSchemaServer"].(map[string]any)["properties"].(map[string]any)["timeout"].(map[string]any)
+	assert.Equal(t, "integer", timeout["type"])
+	assert.Equal(t, float64(5*time.Second), timeout["default"])
+}
+
+func TestSchema_RecursiveRoot(t *testing.T) {
+	data, err := Schema[testSchemaNode]()
+	require.NoError(t, err)
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(data, &schema))
+	children := schema["properties"].(map[string]any)["children"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#"}, children["items"])
+	assert.NotContains(t, schema, "$defs")
+}
+
+func TestSchema_UnsupportedFormat(t *testing.T) {
+	_, err := SchemaFor[testSchemaConfig](Format("ini"))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestSchema_DefaultsToJSON(t *testing.T) {
+	data, err := Schema[testSchemaConfig]()
+	require.NoError(t, err)
+	want, err := SchemaFor[testSchemaConfig](FormatJSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(data))
+}
+
+func TestSchema_InlineMap(t *testing.T) {
+	type config struct {
+		Name  string         `yaml:"name"`
+		Extra map[string]int `yaml:",inline"`
+	}
+	for _, opts := range [][]Option{nil, {Strict()}} {
+		data, err := SchemaFor[config](FormatYAML, opts...)
+		require.NoError(t, err)
+
+		var schema map[string]any
+		require.NoError(t, json.Unmarshal(data, &schema))
+		assert.Equal(t, map[string]any{"type": "integer"}, schema["additionalProperties"])
+		assert.NotContains(t, schema["properties"], "Extra")
+	}
+}
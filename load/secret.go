@@ -0,0 +1,334 @@
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretPrefix starts the string values that refer to secrets.
+const secretPrefix = "secret://"
+
+// redacted replaces the value of a Secret wherever it is printed or
+// encoded.
+const redacted = "[REDACTED]"
+
+var secretType = reflect.TypeFor[Secret]()
+
+// ErrUnknownSecretScheme is returned for secret references whose scheme
+// has no registered resolver.
+var ErrUnknownSecretScheme = errors.New("unknown secret scheme")
+
+// Secret is a string that must not leak into logs or written files. It
+// decodes like a plain string, but printing it with the fmt package or
+// encoding it as JSON, YAML or TOML yields "[REDACTED]". Use Reveal to
+// read the actual value.
+type Secret string
+
+// Reveal returns the value of the secret.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("load.Secret(%q)", redacted)
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+func (s *Secret) UnmarshalText(text []byte) error {
+	*s = Secret(text)
+	return nil
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return redacted, nil
+}
+
+// SecretResolver looks up the value of a secret reference. The reference
+// is the part of a `secret://scheme/reference` value after the scheme.
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface.
+type SecretResolverFunc func(ref string) (string, error)
+
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// SecretResolvers maps the schemes of secret references to the resolvers
+// that handle them.
+type SecretResolvers map[string]SecretResolver
+
+// DefaultSecretResolvers returns the resolvers for the "file" and "env"
+// schemes: `secret://file/etc/app/token` reads /etc/app/token and
+// `secret://env/DB_PASS` reads the DB_PASS environment variable. Running
+// commands is opt-in through ExecSecrets.
+func DefaultSecretResolvers() SecretResolvers {
+	return SecretResolvers{
+		"file": FileSecrets(os.DirFS("/")),
+		"env":  EnvSecrets(nil),
+	}
+}
+
+// FileSecrets resolves references to the contents of files in fsys,
+// without a trailing newline.
+func FileSecrets(fsys fs.FS) SecretResolver {
+	return SecretResolverFunc(func(ref string) (string, error) {
+		data, err := fs.ReadFile(fsys, ref)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	})
+}
+
+// EnvSecrets resolves references to the value of environment variables,
+// looked up through lookup or os.LookupEnv when it is nil. Unset
+// variables are an error.
+func EnvSecrets(lookup LookupFunc) SecretResolver {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return SecretResolverFunc(func(ref string) (string, error) {
+		value, ok := lookup(ref)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUndefinedVariable, ref)
+		}
+		return value, nil
+	})
+}
+
+// ExecSecrets resolves references by running them as a command, split on
+// whitespace, and reading its standard output without a trailing newline:
+// `secret://exec/pass show app/token` runs `pass show app/token`.
+func ExecSecrets() SecretResolver {
+	return SecretResolverFunc(func(ref string) (string, error) {
+		args := strings.Fields(ref)
+		if len(args) == 0 {
+			return "", errors.New("empty command")
+		}
+		var stderr bytes.Buffer
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("%w: %s", err, msg)
+			}
+			return "", err
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	})
+}
+
+// ResolveSecrets replaces string values of the form
+// `secret://scheme/reference` with the value returned by the resolver
+// registered for the scheme. References with an unregistered scheme
+// fail with ErrUnknownSecretScheme.
+//
+// Resolved values stay redacted: they must be decoded into Secret fields,
+// and become Secret values inside generic documents such as
+// map[string]any. Decoding one into any other type, such as a plain
+// string, is an error.
+func ResolveSecrets(resolvers SecretResolvers) Option {
+	return func(o *options) {
+		o.secrets = resolvers
+	}
+}
+
+// secretNodes is the set of nodes whose value was resolved from a secret
+// reference.
+type secretNodes map[*yaml.Node]bool
+
+// resolveSecrets replaces the secret references in a node tree and
+// records the nodes that now hold a secret.
+func resolveSecrets(node *yaml.Node, resolvers SecretResolvers, secrets secretNodes) error {
+	var err error
+	walkNodes(node, "", func(n *yaml.Node, path string) bool {
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!str" || !strings.HasPrefix(n.Value, secretPrefix) {
+			return true
+		}
+		var value string
+		if value, err = resolveSecret(n.Value, resolvers); err != nil {
+			err = fmt.Errorf("%s: %w", path, err)
+			return false
+		}
+		n.Value, n.Style = value, yaml.DoubleQuotedStyle
+		secrets[n] = true
+		return true
+	})
+	return err
+}
+
+// sealSecrets walks a node tree alongside the value decoded from it and
+// turns the resolved secrets held by generic values into Secret values.
+// Secrets decoded into any type other than Secret are an error, as
+// nothing would keep them out of logs and written files. Types with their
+// own unmarshalers are trusted to handle the values they receive.
+func sealSecrets(node *yaml.Node, v reflect.Value, secrets secretNodes, tag string, path string) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return sealSecrets(node.Content[0], v, secrets, tag, path)
+	case yaml.AliasNode:
+		return sealSecrets(node.Alias, v, secrets, tag, path)
+	}
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if secrets[node] {
+		switch {
+		case v.Type() == secretType:
+			return nil
+		case v.Kind() == reflect.Interface && v.CanSet():
+			if s, ok := v.Interface().(string); ok {
+				v.Set(reflect.ValueOf(Secret(s)))
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: secret must be decoded into a load.Secret, not %s", limitPath(path), v.Type())
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		// The dynamic value of an interface is not addressable, so it is
+		// sealed on a copy that replaces it.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := sealSecrets(node, elem, secrets, tag, path); err != nil {
+			return err
+		}
+		if v.CanSet() {
+			v.Set(elem)
+		}
+		return nil
+	}
+	if hasCustomUnmarshaler(v.Type()) {
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		return sealMapping(node, v, secrets, tag, path)
+	case yaml.SequenceNode:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil
+		}
+		for i, child := range node.Content {
+			if i >= v.Len() {
+				break
+			}
+			if err := sealSecrets(child, v.Index(i), secrets, tag, indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sealMapping seals the entries of a mapping node decoded into a struct or
+// a map.
+func sealMapping(node *yaml.Node, v reflect.Value, secrets secretNodes, tag string, path string) error {
+	var fields []structField
+	if v.Kind() == reflect.Struct {
+		fields = structFields(v.Type(), tag)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, child := node.Content[i].Value, node.Content[i+1]
+		childPath := joinPath(path, key)
+		target := v
+		if v.Kind() == reflect.Struct {
+			f, ok := lookupField(fields, key, tag)
+			if !ok {
+				if f, ok = inlineField(fields); !ok {
+					continue
+				}
+			}
+			field, err := v.FieldByIndexErr(f.index)
+			if err != nil {
+				continue
+			}
+			if !f.inline {
+				if err := sealSecrets(child, field, secrets, tag, childPath); err != nil {
+					return err
+				}
+				continue
+			}
+			target = field
+		}
+		if target.Kind() != reflect.Map {
+			continue
+		}
+		mapKey, ok := findMapKey(target, key)
+		if !ok {
+			continue
+		}
+		elem := reflect.New(target.Type().Elem()).Elem()
+		elem.Set(target.MapIndex(mapKey))
+		if err := sealSecrets(child, elem, secrets, tag, childPath); err != nil {
+			return err
+		}
+		target.SetMapIndex(mapKey, elem)
+	}
+	return nil
+}
+
+// findMapKey finds the key of a map that a document key decoded into.
+func findMapKey(m reflect.Value, key string) (reflect.Value, bool) {
+	if m.Type().Key().Kind() == reflect.String {
+		k := reflect.ValueOf(key).Convert(m.Type().Key())
+		return k, m.MapIndex(k).IsValid()
+	}
+	iter := m.MapRange()
+	for iter.Next() {
+		if fmt.Sprint(iter.Key().Interface()) == key {
+			return iter.Key(), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// resolveSecret resolves a single secret reference.
+func resolveSecret(value string, resolvers SecretResolvers) (string, error) {
+	scheme, ref, _ := strings.Cut(strings.TrimPrefix(value, secretPrefix), "/")
+	resolver, ok := resolvers[scheme]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownSecretScheme, scheme)
+	}
+	resolved, err := resolver.Resolve(ref)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve secret://%s reference: %w", scheme, err)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,163 @@
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSecretConfig struct {
+	User     string `json:"user" yaml:"user" toml:"user"`
+	Password Secret `json:"password" yaml:"password" toml:"password"`
+	Token    Secret `json:"token" yaml:"token" toml:"token"`
+}
+
+func testSecretResolvers() SecretResolvers {
+	return SecretResolvers{
+		"file": FileSecrets(fstest.MapFS{"etc/app/token": {Data: []byte("tok-123\n")}}),
+		"env":  EnvSecrets(testLookup(map[string]string{"DB_PASS": "hunter2"})),
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{
+			name:   "YAML",
+			format: FormatYAML,
+			input:  "user: admin\npassword: secret://env/DB_PASS\ntoken: secret://file/etc/app/token\n",
+		},
+		{
+			name:   "JSON",
+			format: FormatJSON,
+			input:  `{"user": "admin", "password": "secret://env/DB_PASS", "token": "secret://file/etc/app/token"}`,
+		},
+		{
+			name:   "TOML",
+			format: FormatTOML,
+			input:  "user = \"admin\"\npassword = \"secret://env/DB_PASS\"\ntoken = \"secret://file/etc/app/token\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode[testSecretConfig](tt.format, strings.NewReader(tt.input), []Option{ResolveSecrets(testSecretResolvers())})
+			require.NoError(t, err)
+			assert.Equal(t, "admin", got.User)
+			assert.Equal(t, "hunter2", got.Password.Reveal())
+			assert.Equal(t, "tok-123", got.Token.Reveal())
+		})
+	}
+}
+
+func TestResolveSecrets_AfterInterpolation(t *testing.T) {
+	input := "password: secret://env/${PASS_VAR}\n"
+	got, err := FromYAML[testSecretConfig](strings.NewReader(input),
+		ExpandEnv(testLookup(map[string]string{"PASS_VAR": "DB_PASS"})),
+		ResolveSecrets(testSecretResolvers()))
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", got.Password.Reveal())
+}
+
+func TestResolveSecrets_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "UnknownScheme", input: "token: secret://vault/app\n", wantErr: `token: unknown secret scheme "vault"`},
+		{name: "MissingVariable", input: "password: secret://env/NOPE\n", wantErr: "password: cannot resolve secret://env reference"},
+		{name: "MissingFile", input: "token: secret://file/etc/missing\n", wantErr: "file does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromYAML[testSecretConfig](strings.NewReader(tt.input), ResolveSecrets(testSecretResolvers()))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := FromYAML[testSecretConfig](strings.NewReader("token: secret://exec/true\n"), ResolveSecrets(DefaultSecretResolvers()))
+	assert.ErrorIs(t, err, ErrUnknownSecretScheme)
+}
+
+func TestResolveSecrets_GenericDocuments(t *testing.T) {
+	opts := []Option{ResolveSecrets(testSecretResolvers())}
+
+	doc, err := FromYAML[map[string]any](strings.NewReader("user: admin\npw: secret://env/DB_PASS\ntokens:\n  - secret://file/etc/app/token\n"), opts...)
+	require.NoError(t, err)
+	assert.Equal(t, Secret("hunter2"), doc["pw"])
+	assert.Equal(t, []any{Secret("tok-123")}, doc["tokens"])
+
+	var out bytes.Buffer
+	require.NoError(t, ToYAML(&out, doc))
+	assert.Equal(t, "pw: '[REDACTED]'\ntokens:\n  - '[REDACTED]'\nuser: admin\n", out.String())
+	assert.NotContains(t, fmt.Sprint(doc), "hunter2")
+
+	jsonDoc, err := FromJSON[any](strings.NewReader(`{"db": {"pw": "secret://env/DB_PASS"}}`), opts...)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"db": map[string]any{"pw": Secret("hunter2")}}, jsonDoc)
+
+	type mixed struct {
+		User  string         `yaml:"user"`
+		Extra map[string]any `yaml:"extra"`
+	}
+	got, err := FromYAML[mixed](strings.NewReader("user: admin\nextra:\n  pw: secret://env/DB_PASS\n"), opts...)
+	require.NoError(t, err)
+	assert.Equal(t, Secret("hunter2"), got.Extra["pw"])
+}
+
+func TestResolveSecrets_PlainTargets(t *testing.T) {
+	type plain struct {
+		Password string            `json:"password" yaml:"password"`
+		Labels   map[string]string `json:"labels" yaml:"labels"`
+	}
+	opts := []Option{ResolveSecrets(testSecretResolvers())}
+
+	_, err := FromYAML[plain](strings.NewReader("password: secret://env/DB_PASS\n"), opts...)
+	assert.EqualError(t, err, "password: secret must be decoded into a load.Secret, not string")
+
+	_, err = FromJSON[plain](strings.NewReader(`{"labels": {"pw": "secret://env/DB_PASS"}}`), opts...)
+	assert.EqualError(t, err, "labels.pw: secret must be decoded into a load.Secret, not string")
+
+	_, err = FromYAML[string](strings.NewReader("secret://env/DB_PASS\n"), opts...)
+	assert.EqualError(t, err, "(root): secret must be decoded into a load.Secret, not string")
+}
+
+func TestExecSecrets(t *testing.T) {
+	resolvers := SecretResolvers{"exec": ExecSecrets()}
+	got, err := FromYAML[testSecretConfig](strings.NewReader("token: secret://exec/echo s3cr3t\n"), ResolveSecrets(resolvers))
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", got.Token.Reveal())
+
+	_, err = FromYAML[testSecretConfig](strings.NewReader("token: secret://exec/\n"), ResolveSecrets(resolvers))
+	assert.ErrorContains(t, err, "empty command")
+}
+
+func TestSecret_Redacted(t *testing.T) {
+	cfg := testSecretConfig{User: "admin", Password: "hunter2", Token: "tok-123"}
+
+	assert.NotContains(t, fmt.Sprintf("%v %+v %s", cfg, cfg, cfg.Password), "hunter2")
+	assert.NotContains(t, fmt.Sprintf("%#v", cfg), "hunter2")
+
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2")
+
+	var buf bytes.Buffer
+	require.NoError(t, ToYAML(&buf, cfg))
+	assert.Equal(t, "user: admin\npassword: '[REDACTED]'\ntoken: '[REDACTED]'\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, ToTOML(&buf, cfg))
+	assert.NotContains(t, buf.String(), "hunter2")
+}
@@ -0,0 +1,110 @@
+package load
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrTrailingData is returned in strict mode when a JSON document is
+// followed by anything other than whitespace.
+var ErrTrailingData = errors.New("unexpected trailing data after document")
+
+// UnknownFieldsError is returned in strict mode when a document contains
+// keys that do not map onto the target type.
+type UnknownFieldsError struct {
+	// Paths lists the offending key paths, e.g. "servers[2].timout".
+	Paths []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("unknown fields: %s", strings.Join(e.Paths, ", "))
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	yamlUnmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// checkUnknownFields walks a generic document alongside the target type
+// and reports every key that the decoder would silently drop.
+func checkUnknownFields(doc any, t reflect.Type, tag string) error {
+	var paths []string
+	collectUnknownFields(doc, t, tag, "", &paths)
+	if len(paths) > 0 {
+		return &UnknownFieldsError{Paths: paths}
+	}
+	return nil
+}
+
+func collectUnknownFields(doc any, t reflect.Type, tag string, path string, out *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if hasCustomUnmarshaler(t) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := structFields(t, tag)
+		forEachEntry(doc, func(key string, value any) {
+			f, ok := lookupField(fields, key, tag)
+			if ok {
+				collectUnknownFields(value, f.field.Type, tag, joinPath(path, key), out)
+				return
+			}
+			if f, ok := inlineField(fields); ok {
+				collectUnknownFields(value, f.field.Type.Elem(), tag, joinPath(path, key), out)
+				return
+			}
+			*out = append(*out, joinPath(path, key))
+		})
+	case reflect.Map:
+		forEachEntry(doc, func(key string, value any) {
+			collectUnknownFields(value, t.Elem(), tag, joinPath(path, key), out)
+		})
+	case reflect.Slice, reflect.Array:
+		if items, ok := doc.([]any); ok {
+			for i, item := range items {
+				collectUnknownFields(item, t.Elem(), tag, indexPath(path, i), out)
+			}
+		}
+	}
+}
+
+// hasCustomUnmarshaler reports whether values of the type decode themselves,
+// in which case their keys cannot be checked against struct fields.
+func hasCustomUnmarshaler(t reflect.Type) bool {
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(jsonUnmarshalerType) ||
+		ptr.Implements(yamlUnmarshalerType) ||
+		ptr.Implements(textUnmarshalerType)
+}
+
+// forEachEntry iterates over the entries of a generic mapping, whichever
+// map type the decoder produced for it.
+func forEachEntry(doc any, fn func(key string, value any)) {
+	switch m := doc.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			fn(key, m[key])
+		}
+	case map[any]any:
+		keys := make(map[string]any, len(m))
+		for k, v := range m {
+			keys[fmt.Sprint(k)] = v
+		}
+		for _, key := range slices.Sorted(maps.Keys(keys)) {
+			fn(key, keys[key])
+		}
+	}
+}
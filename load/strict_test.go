@@ -0,0 +1,137 @@
+package load
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testServer struct {
+	Host    string        `json:"host" yaml:"host" toml:"host"`
+	Port    int           `json:"port" yaml:"port" toml:"port"`
+	Timeout time.Duration `json:"-" yaml:"timeout" toml:"-"`
+}
+
+type testCluster struct {
+	Name    string                `json:"name" yaml:"name" toml:"name"`
+	Servers []testServer          `json:"servers" yaml:"servers" toml:"servers"`
+	Zones   map[string]testServer `json:"zones" yaml:"zones" toml:"zones"`
+}
+
+func TestStrict_UnknownFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		load  func(data string) error
+		data  string
+		paths []string
+	}{
+		{
+			name: "json",
+			load: func(data string) error {
+				_, err := FromJSON[testCluster](strings.NewReader(data), Strict())
+				return err
+			},
+			data:  `{"name":"a","nmae":"b","servers":[{"host":"x"},{"host":"y","prot":1}],"zones":{"eu":{"hots":"z"}}}`,
+			paths: []string{"nmae", "servers[1].prot", "zones.eu.hots"},
+		},
+		{
+			name: "yaml",
+			load: func(data string) error {
+				_, err := FromYAML[testCluster](strings.NewReader(data), Strict())
+				return err
+			},
+			data:  "name: a\nservers:\n  - host: x\n    timout: 5s\nzones:\n  eu:\n    hots: z\nextra: true\n",
+			paths: []string{"extra", "servers[0].timout", "zones.eu.hots"},
+		},
+		{
+			name: "toml",
+			load: func(data string) error {
+				_, err := FromTOML[testCluster](strings.NewReader(data), Strict())
+				return err
+			},
+			data:  "name = \"a\"\nextra = 1\n[[servers]]\nhost = \"x\"\nprot = 1\n",
+			paths: []string{"extra", "servers[0].prot"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.load(tt.data)
+			var unknown *UnknownFieldsError
+			require.ErrorAs(t, err, &unknown)
+			assert.Equal(t, tt.paths, unknown.Paths)
+			for _, path := range tt.paths {
+				assert.Contains(t, err.Error(), path)
+			}
+		})
+	}
+}
+
+func TestStrict_KnownFieldsAccepted(t *testing.T) {
+	got, err := FromJSON[testCluster](strings.NewReader(`{"Name":"a","servers":[{"host":"x","port":1}]}`), Strict())
+	require.NoError(t, err)
+	assert.Equal(t, "a", got.Name)
+
+	gotYAML, err := FromYAML[testCluster](strings.NewReader("name: a\nservers:\n  - host: x\n    timeout: 5s\n"), Strict())
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, gotYAML.Servers[0].Timeout)
+}
+
+func TestStrict_EmbeddedStructs(t *testing.T) {
+	type base struct {
+		ID string `json:"id" yaml:"id"`
+	}
+	type inlined struct {
+		base `yaml:",inline"`
+		Name string `json:"name" yaml:"name"`
+	}
+
+	_, err := FromJSON[inlined](strings.NewReader(`{"id":"1","name":"a"}`), Strict())
+	assert.NoError(t, err)
+
+	_, err = FromYAML[inlined](strings.NewReader("id: \"1\"\nname: a\n"), Strict())
+	assert.NoError(t, err)
+}
+
+func TestStrict_InlineMap(t *testing.T) {
+	type extended struct {
+		Name  string                `yaml:"name"`
+		Extra map[string]testServer `yaml:",inline"`
+	}
+
+	got, err := FromYAML[extended](strings.NewReader("name: a\nprimary:\n  host: x\n  port: 1\n"), Strict())
+	require.NoError(t, err)
+	assert.Equal(t, extended{Name: "a", Extra: map[string]testServer{"primary": {Host: "x", Port: 1}}}, got)
+
+	_, err = FromYAML[extended](strings.NewReader("name: a\nprimary:\n  hots: x\n"), Strict())
+	var unknown *UnknownFieldsError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, []string{"primary.hots"}, unknown.Paths)
+}
+
+func TestStrict_TrailingJSONData(t *testing.T) {
+	_, err := FromJSON[testPerson](strings.NewReader(`{"name":"Ada"} {"name":"Bob"}`), Strict())
+	assert.ErrorIs(t, err, ErrTrailingData)
+
+	_, err = FromJSON[testPerson](strings.NewReader("{\"name\":\"Ada\"}\n\n"), Strict())
+	assert.NoError(t, err)
+
+	_, err = FromJSON[testPerson](strings.NewReader(`{"name":"Ada"} {"name":"Bob"}`), Strict(), ExpandEnv(testLookup(nil)))
+	assert.ErrorIs(t, err, ErrTrailingData)
+
+	_, err = FromJSON[testPerson](strings.NewReader(`{"name":"Ada"} {"name":"Bob"}`), Strict(), MaxDepth(8))
+	assert.ErrorIs(t, err, ErrTrailingData)
+
+	got, err := FromJSON[testPerson](strings.NewReader(`{"name":"Ada"} {"name":"Bob"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", got.Name)
+}
+
+func TestStrict_DisabledByDefault(t *testing.T) {
+	got, err := FromYAML[testPerson](strings.NewReader("name: Ada\ntimout: 5\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", got.Name)
+}
@@ -0,0 +1,211 @@
+package load
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document-level options operate on a yaml.Node tree whatever the source
+// format; JSON and TOML are converted from their generic decoded form.
+
+// nodeFromValue converts a generic decoded value into a YAML node tree.
+func nodeFromValue(v any) (*yaml.Node, error) {
+	switch val := v.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case *yaml.Node:
+		return val, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: val, Style: yaml.DoubleQuotedStyle}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(val)}, nil
+	case json.Number:
+		tag := "!!float"
+		if _, err := strconv.ParseInt(val.String(), 10, 64); err == nil {
+			tag = "!!int"
+		} else if _, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: val.String()}, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(val)}, nil
+	case float32:
+		return nodeFromValue(float64(val))
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: formatFloat(val)}, nil
+	case time.Time:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: val.Format(time.RFC3339Nano)}, nil
+	case encoding.TextMarshaler:
+		text, err := val.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return nodeFromValue(string(text))
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range val {
+			child, err := nodeFromValue(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case map[string]any:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range slices.Sorted(maps.Keys(val)) {
+			child, err := nodeFromValue(val[key])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		return node, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(val); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+// valueFromNode converts a YAML node tree back into generic values made
+// of maps, slices and scalars.
+func valueFromNode(node *yaml.Node) (any, error) {
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return nil, err
+	}
+	return normalizeValue(v), nil
+}
+
+// normalizeValue rewrites maps with non-string keys into string-keyed
+// maps so that generic values can be encoded as JSON and TOML.
+func normalizeValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			val[k] = normalizeValue(item)
+		}
+		return val
+	case map[any]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = normalizeValue(item)
+		}
+		return out
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeValue(item)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+// walkNodes visits the value nodes of a tree along with their document
+// path until fn returns false.
+func walkNodes(node *yaml.Node, path string, fn func(node *yaml.Node, path string) bool) bool {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if !walkNodes(child, path, fn) {
+				return false
+			}
+		}
+		return true
+	case yaml.MappingNode:
+		if !fn(node, path) {
+			return false
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !walkNodes(node.Content[i+1], joinPath(path, node.Content[i].Value), fn) {
+				return false
+			}
+		}
+		return true
+	case yaml.SequenceNode:
+		if !fn(node, path) {
+			return false
+		}
+		for i, child := range node.Content {
+			if !walkNodes(child, indexPath(path, i), fn) {
+				return false
+			}
+		}
+		return true
+	default:
+		return fn(node, path)
+	}
+}
+
+// documentContent unwraps a document node to its root content node.
+func documentContent(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+	return node
+}
+
+// formatFloat renders a float the way YAML expects it.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// transformsNodes reports whether any option needs the document as a
+// node tree before it is decoded.
+func (o *options) transformsNodes() bool {
+	return o.lookupEnv != nil || o.includeFS != nil || o.secrets != nil || len(o.migrations) > 0 || len(o.patches) > 0
+}
+
+// transformNode applies the document-level options to a node tree once
+// its limits are checked: includes are resolved, then migrations, patches,
+// environment expansion and secret resolution run in that order. It
+// returns the nodes that hold resolved secrets.
+func transformNode(node *yaml.Node, o *options) (secretNodes, error) {
+	if o.includeFS != nil {
+		if err := resolveIncludes(node, o); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.migrations) > 0 {
+		if err := migrateNode(node, o); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.patches) > 0 {
+		if err := patchNode(node, o); err != nil {
+			return nil, err
+		}
+	}
+	if o.lookupEnv != nil {
+		if err := expandEnv(node, o.lookupEnv); err != nil {
+			return nil, err
+		}
+	}
+	secrets := secretNodes{}
+	if o.secrets != nil {
+		if err := resolveSecrets(node, o.secrets, secrets); err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
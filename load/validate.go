@@ -0,0 +1,236 @@
+package load
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Violation describes a single failed validation rule.
+type Violation struct {
+	// Path is the document path of the offending field, e.g. "servers[2].port".
+	Path string
+	// Rule is the validation rule that failed, e.g. "max=65535".
+	Rule string
+	// Message describes the failure in human-readable form.
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// ValidationError aggregates every violation found in a value.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Violations)+1)
+	lines = append(lines, "validation failed:")
+	for _, v := range e.Violations {
+		lines = append(lines, "  "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WithValidation checks the decoded value against the rules declared in
+// its `validate` struct tags. Supported rules are:
+//
+//   - required: the field must not hold its zero value
+//   - min=N, max=N: bounds for numbers, or for the length of strings,
+//     slices and maps; durations accept values such as "1s"
+//   - oneof=a b c: the field must hold one of the space-separated values
+//   - regex=PATTERN: strings must match the pattern; as patterns may
+//     contain commas, this rule must come last
+func WithValidation() Option {
+	return func(o *options) {
+		o.validate = true
+	}
+}
+
+// Validate checks a value against the rules declared in its `validate`
+// struct tags and returns a *ValidationError listing every violation.
+// Field paths are named after the `json` struct tags.
+func Validate(v any) error {
+	return validate(v, "json")
+}
+
+// validate checks a value, naming field paths after the given struct tag.
+func validate(v any, tag string) error {
+	var violations []Violation
+	validateValue(reflect.ValueOf(v), tag, "", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, tag string, path string, out *[]Violation) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range structFields(v.Type(), tag) {
+			field := v.FieldByIndex(f.index)
+			fieldPath := joinPath(path, f.key)
+			if f.inline {
+				fieldPath = path
+			}
+			rules := f.field.Tag.Get("validate")
+			if rules == "-" {
+				continue
+			}
+			if rules != "" {
+				*out = append(*out, checkRules(field, rules, fieldPath)...)
+			}
+			validateValue(field, tag, fieldPath, out)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), tag, indexPath(path, i), out)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		for _, key := range keys {
+			validateValue(v.MapIndex(key), tag, joinPath(path, fmt.Sprint(key.Interface())), out)
+		}
+	}
+}
+
+// splitRules splits a validate tag into its rules, keeping everything
+// after "regex=" as a single pattern.
+func splitRules(rules string) []string {
+	var out []string
+	for rules != "" {
+		if strings.HasPrefix(rules, "regex=") {
+			return append(out, rules)
+		}
+		rule, rest, _ := strings.Cut(rules, ",")
+		if rule != "" {
+			out = append(out, rule)
+		}
+		rules = rest
+	}
+	return out
+}
+
+// checkRules applies the rules of a validate tag to a single field.
+func checkRules(field reflect.Value, rules string, path string) []Violation {
+	var violations []Violation
+	fail := func(rule string, format string, args ...any) {
+		violations = append(violations, Violation{Path: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	parts := splitRules(rules)
+	for _, rule := range parts {
+		if rule == "required" && field.IsZero() {
+			fail(rule, "is required")
+			return violations
+		}
+	}
+
+	v := field
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return violations
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range parts {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+		case "min", "max":
+			actual, bound, measure, err := compareBound(v, arg)
+			if err != nil {
+				fail(rule, "invalid rule %q: %v", rule, err)
+				continue
+			}
+			if name == "min" && actual < bound {
+				fail(rule, "%smust be at least %s", measure, arg)
+			}
+			if name == "max" && actual > bound {
+				fail(rule, "%smust be at most %s", measure, arg)
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			actual := fmt.Sprint(v.Interface())
+			found := false
+			for _, a := range allowed {
+				if a == actual {
+					found = true
+					break
+				}
+			}
+			if !found {
+				fail(rule, "must be one of [%s], got %q", strings.Join(allowed, " "), actual)
+			}
+		case "regex":
+			pattern, err := regexp.Compile(arg)
+			if err != nil {
+				fail(rule, "invalid rule %q: %v", rule, err)
+				continue
+			}
+			if v.Kind() != reflect.String {
+				fail(rule, "invalid rule %q: field is not a string", rule)
+				continue
+			}
+			if !pattern.MatchString(v.String()) {
+				fail(rule, "must match %q", arg)
+			}
+		default:
+			fail(rule, "unknown rule %q", rule)
+		}
+	}
+	return violations
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// compareBound returns the quantity of a value that min and max rules
+// apply to, the parsed bound, and a prefix naming that quantity when it
+// is not the value itself.
+func compareBound(v reflect.Value, arg string) (float64, float64, string, error) {
+	if v.Type() == durationType {
+		bound, err := time.ParseDuration(arg)
+		if err != nil {
+			if n, numErr := strconv.ParseInt(arg, 10, 64); numErr == nil {
+				bound = time.Duration(n)
+				err = nil
+			}
+		}
+		return float64(v.Int()), float64(bound), "", err
+	}
+
+	bound, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), bound, "", nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), bound, "", nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), bound, "", nil
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), bound, "length ", nil
+	default:
+		return 0, 0, "", fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+}
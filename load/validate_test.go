@@ -0,0 +1,121 @@
+package load
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValidatedServer struct {
+	Host    string        `json:"host" yaml:"host" validate:"required,regex=^[a-z0-9.-]+$"`
+	Port    int           `json:"port" yaml:"port" validate:"min=1,max=65535"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout" validate:"max=1m"`
+}
+
+type testValidatedConfig struct {
+	Name    string                `json:"name" yaml:"name" validate:"required,min=3"`
+	Mode    string                `json:"mode" yaml:"mode" validate:"oneof=fast safe"`
+	Servers []testValidatedServer `json:"servers" yaml:"servers" validate:"min=1"`
+	Owner   *testValidatedServer  `json:"owner" yaml:"owner"`
+	Zones   map[string]testValidatedServer
+}
+
+func TestValidate(t *testing.T) {
+	valid := testValidatedConfig{
+		Name:    "api",
+		Mode:    "fast",
+		Servers: []testValidatedServer{{Host: "a.example", Port: 80}},
+	}
+	assert.NoError(t, Validate(valid))
+	assert.NoError(t, Validate(&valid))
+}
+
+func TestValidate_Violations(t *testing.T) {
+	cfg := testValidatedConfig{
+		Name: "ab",
+		Mode: "slow",
+		Servers: []testValidatedServer{
+			{Host: "ok", Port: 80},
+			{Host: "Not Valid", Port: 0},
+			{Port: 70000, Timeout: 2 * time.Minute},
+		},
+		Owner: &testValidatedServer{Port: 1},
+		Zones: map[string]testValidatedServer{"eu": {Host: "eu", Port: -1}},
+	}
+
+	err := Validate(cfg)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	var paths []string
+	for _, v := range validationErr.Violations {
+		paths = append(paths, v.Path+" "+v.Rule)
+	}
+	assert.Equal(t, []string{
+		"name min=3",
+		"mode oneof=fast safe",
+		"servers[1].host regex=^[a-z0-9.-]+$",
+		"servers[1].port min=1",
+		"servers[2].host required",
+		"servers[2].port max=65535",
+		"servers[2].timeout max=1m",
+		"owner.host required",
+		"Zones.eu.port min=1",
+	}, paths)
+	assert.Contains(t, err.Error(), "servers[2].port: must be at most 65535")
+	assert.Contains(t, err.Error(), "name: length must be at least 3")
+}
+
+func TestValidate_MapOrder(t *testing.T) {
+	zones := map[string]testValidatedServer{}
+	for _, name := range []string{"us", "eu", "ap", "sa", "af"} {
+		zones[name] = testValidatedServer{Host: name}
+	}
+	cfg := testValidatedConfig{Name: "api", Mode: "fast", Servers: []testValidatedServer{{Host: "a", Port: 1}}, Zones: zones}
+
+	for range 10 {
+		var validationErr *ValidationError
+		require.ErrorAs(t, Validate(cfg), &validationErr)
+		var paths []string
+		for _, v := range validationErr.Violations {
+			paths = append(paths, v.Path)
+		}
+		assert.Equal(t, []string{"Zones.af.port", "Zones.ap.port", "Zones.eu.port", "Zones.sa.port", "Zones.us.port"}, paths)
+	}
+}
+
+func TestValidate_RequiredSlice(t *testing.T) {
+	type config struct {
+		Items []string `json:"items" validate:"required"`
+	}
+	err := Validate(config{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "items: is required")
+}
+
+func TestValidate_InvalidRule(t *testing.T) {
+	type config struct {
+		Port int `json:"port" validate:"between=1"`
+	}
+	err := Validate(config{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown rule "between=1"`)
+}
+
+func TestWithValidation(t *testing.T) {
+	data := "name: api\nmode: fast\nservers:\n  - host: a\n    port: 1\n  - host: b\n    port: 0\n"
+
+	_, err := FromYAML[testValidatedConfig](strings.NewReader(data))
+	assert.NoError(t, err)
+
+	_, err = FromYAML[testValidatedConfig](strings.NewReader(data), WithValidation())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "servers[1].port: must be at least 1")
+
+	_, err = FromJSON[testValidatedConfig](strings.NewReader(`{"name":"api","mode":"fast"}`), WithValidation())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "servers: length must be at least 1")
+}
@@ -0,0 +1,159 @@
+package load
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// defaultPollInterval is how often Watch checks a file unless PollInterval
+// is provided.
+const defaultPollInterval = time.Second
+
+// PollInterval sets how often Watch checks the watched file for changes.
+func PollInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = d
+	}
+}
+
+// Reload reports the outcome of reloading a watched file.
+type Reload[T any] struct {
+	// Value is the newly loaded value, or the last good value when Err is
+	// set.
+	Value T
+	// Err is the error that prevented the file from being reloaded.
+	Err error
+}
+
+// Watcher keeps a value loaded from a file up to date. It is created by
+// Watch.
+type Watcher[T any] struct {
+	fsys    fs.FS
+	name    string
+	opts    []Option
+	reloads chan Reload[T]
+
+	mu    sync.RWMutex
+	value T
+
+	data    []byte
+	readErr error
+}
+
+// Watch loads the file at name in fsys like FromFS, then polls it for
+// changes until ctx is done, e.g. when the context of a command created by
+// commandline.New is cancelled on SIGTERM or SIGINT. Polling needs no
+// file system notifications, so any fs.FS works, including the one
+// returned by fileutils.RootDirFromContext.
+//
+// Changed files are decoded with the provided options. Validation always
+// runs, as if WithValidation were given, on the initial load as well as on
+// every reload. Every reload is published on the Reloads channel; when the
+// new file cannot be read, decoded or validated the watcher keeps the last
+// good value and publishes the error instead. Watch fails if the initial
+// load fails.
+func Watch[T any](ctx context.Context, fsys fs.FS, name string, opts ...Option) (*Watcher[T], error) {
+	opts = append([]Option{WithValidation()}, opts...)
+	w := &Watcher[T]{
+		fsys:    fsys,
+		name:    name,
+		opts:    opts,
+		reloads: make(chan Reload[T], 1),
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := fromBytes[T](name, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	w.value, w.data = value, data
+
+	interval := newOptions(opts).pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	go w.run(ctx, interval)
+	return w, nil
+}
+
+// Value returns the last value that was loaded successfully.
+func (w *Watcher[T]) Value() T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.value
+}
+
+// Reloads returns the channel on which reloads are published. Publishing
+// never blocks polling: the channel buffers a single reload, and a reload
+// that has not been received yet is dropped in favour of the next one, so
+// a slow receiver only sees the latest outcome. Value always returns the
+// last good value regardless. The channel is closed once the watcher
+// stops.
+func (w *Watcher[T]) Reloads() <-chan Reload[T] {
+	return w.reloads
+}
+
+// run polls the file until ctx is done.
+func (w *Watcher[T]) run(ctx context.Context, interval time.Duration) {
+	defer close(w.reloads)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll reloads the file if its contents changed since the last poll.
+func (w *Watcher[T]) poll() {
+	data, err := fs.ReadFile(w.fsys, w.name)
+	if err != nil {
+		// Only report a read error once, e.g. while an editor replaces
+		// the file.
+		if w.readErr == nil || w.readErr.Error() != err.Error() {
+			w.publish(Reload[T]{Value: w.Value(), Err: err})
+		}
+		w.readErr = err
+		return
+	}
+	w.readErr = nil
+	if bytes.Equal(data, w.data) {
+		return
+	}
+	w.data = data
+
+	value, err := fromBytes[T](w.name, data, w.opts)
+	if err != nil {
+		w.publish(Reload[T]{Value: w.Value(), Err: err})
+		return
+	}
+	w.mu.Lock()
+	w.value = value
+	w.mu.Unlock()
+	w.publish(Reload[T]{Value: value})
+}
+
+// publish sends a reload, replacing one the receiver has not taken yet.
+func (w *Watcher[T]) publish(r Reload[T]) {
+	for {
+		select {
+		case w.reloads <- r:
+			return
+		default:
+		}
+		select {
+		case <-w.reloads:
+		default:
+		}
+	}
+}
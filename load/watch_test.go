@@ -0,0 +1,146 @@
+package load
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMutableFS is a file system whose files can be replaced while a
+// watcher reads it.
+type testMutableFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+func (m *testMutableFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := fstest.MapFS{}
+	for k, v := range m.files {
+		copied := *v
+		files[k] = &copied
+	}
+	return files.Open(name)
+}
+
+func (m *testMutableFS) write(name string, data string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = &fstest.MapFile{Data: []byte(data)}
+}
+
+func (m *testMutableFS) remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, name)
+}
+
+func nextReload[T any](t *testing.T, w *Watcher[T]) Reload[T] {
+	t.Helper()
+	select {
+	case r, ok := <-w.Reloads():
+		require.True(t, ok, "reloads channel closed")
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a reload")
+		return Reload[T]{}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	fsys := &testMutableFS{files: fstest.MapFS{}}
+	fsys.write("server.yaml", "host: a.example\nport: 80\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := Watch[testValidatedServer](ctx, fsys, "server.yaml", PollInterval(5*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, 80, w.Value().Port)
+
+	fsys.write("server.yaml", "host: a.example\nport: 8080\n")
+	r := nextReload(t, w)
+	require.NoError(t, r.Err)
+	assert.Equal(t, 8080, r.Value.Port)
+	assert.Equal(t, 8080, w.Value().Port)
+}
+
+func TestWatch_KeepsLastGoodValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(fsys *testMutableFS)
+		wantErr string
+	}{
+		{
+			name:    "Malformed",
+			change:  func(fsys *testMutableFS) { fsys.write("server.yaml", "host: [\n") },
+			wantErr: "server.yaml",
+		},
+		{
+			name:    "Invalid",
+			change:  func(fsys *testMutableFS) { fsys.write("server.yaml", "host: a.example\nport: 70000\n") },
+			wantErr: "port: must be at most 65535",
+		},
+		{
+			name:    "Removed",
+			change:  func(fsys *testMutableFS) { fsys.remove("server.yaml") },
+			wantErr: "file does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := &testMutableFS{files: fstest.MapFS{}}
+			fsys.write("server.yaml", "host: a.example\nport: 80\n")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			w, err := Watch[testValidatedServer](ctx, fsys, "server.yaml", PollInterval(5*time.Millisecond))
+			require.NoError(t, err)
+
+			tt.change(fsys)
+			r := nextReload(t, w)
+			require.Error(t, r.Err)
+			assert.Contains(t, r.Err.Error(), tt.wantErr)
+			assert.Equal(t, 80, r.Value.Port)
+			assert.Equal(t, 80, w.Value().Port)
+
+			fsys.write("server.yaml", "host: a.example\nport: 81\n")
+			r = nextReload(t, w)
+			require.NoError(t, r.Err)
+			assert.Equal(t, 81, w.Value().Port)
+		})
+	}
+}
+
+func TestWatch_StopsWhenContextDone(t *testing.T) {
+	fsys := fstest.MapFS{"server.json": {Data: []byte(`{"host": "a", "port": 80}`)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := Watch[testValidatedServer](ctx, fsys, "server.json", PollInterval(5*time.Millisecond))
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case _, ok := <-w.Reloads():
+		assert.False(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher did not stop")
+	}
+	assert.Equal(t, "a", w.Value().Host)
+}
+
+func TestWatch_InitialLoadFails(t *testing.T) {
+	fsys := fstest.MapFS{"server.json": {Data: []byte(`{"port": 80}`)}}
+	_, err := Watch[testValidatedServer](context.Background(), fsys, "server.json")
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	_, err = Watch[testValidatedServer](context.Background(), fsys, "missing.json")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,345 @@
+package load
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLDocument is a YAML document that can be edited in place. Values are
+// addressed by key paths such as "servers[0].port", or JSONPath-style
+// `$.labels["app.kubernetes.io/name"]`, and edits leave the rest of the
+// document untouched: comments, key order, anchors and quoting styles are
+// kept when it is written back out.
+type YAMLDocument struct {
+	root   yaml.Node
+	indent int
+}
+
+// ReadYAMLDocument parses the first document of a YAML stream for
+// editing. An empty stream yields an empty document.
+func ReadYAMLDocument(r io.Reader) (*YAMLDocument, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &YAMLDocument{indent: detectIndent(data)}
+	if err := yaml.Unmarshal(data, &d.root); err != nil {
+		return nil, yamlDecodeError(nil, err)
+	}
+	return d, nil
+}
+
+// Get decodes the value at path into the value pointed to by v. It fails
+// with ErrPathNotFound when the path does not exist.
+func (d *YAMLDocument) Get(path string, v any) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	node := d.content()
+	if node == nil {
+		if len(segments) > 0 {
+			return fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+		return nil
+	}
+	for i, s := range segments {
+		node = childNode(node, s)
+		if node == nil {
+			return fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(segments[:i+1]))
+		}
+	}
+	return node.Decode(v)
+}
+
+// Has reports whether a value exists at path.
+func (d *YAMLDocument) Has(path string) bool {
+	var discard yaml.Node
+	return d.Get(path, &discard) == nil
+}
+
+// Set stores value at path, encoded as ToYAML would encode it. Missing
+// mappings along the path are created, and an index equal to the length
+// of a sequence appends to it. A replaced value keeps its comments and
+// anchor.
+func (d *YAMLDocument) Set(path string, value any) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	e := &encoder{format: FormatYAML, tag: "yaml"}
+	replacement, err := e.node(reflect.ValueOf(value))
+	if err != nil {
+		return fmt.Errorf("cannot encode value for %s: %w", path, err)
+	}
+
+	if d.root.Kind == 0 {
+		d.root = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(d.root.Content) == 0 {
+		if len(segments) == 0 {
+			d.root.Content = []*yaml.Node{replacement}
+			return nil
+		}
+		d.root.Content = []*yaml.Node{emptyContainer(segments[0])}
+	}
+
+	node := resolveAlias(d.root.Content[0])
+	for i, s := range segments {
+		last := i == len(segments)-1
+		child := directChild(node, s)
+		if child == nil {
+			var created *yaml.Node
+			if last {
+				created = replacement
+			} else {
+				created = emptyContainer(segments[i+1])
+			}
+			if err := addChild(node, s, created); err != nil {
+				return fmt.Errorf("cannot set %s: %w", formatPath(segments[:i+1]), err)
+			}
+			if last {
+				return nil
+			}
+			node = created
+			continue
+		}
+		if last {
+			replaceNode(child, replacement)
+			return nil
+		}
+		node = resolveAlias(child)
+	}
+	replaceNode(d.root.Content[0], replacement)
+	return nil
+}
+
+// Delete removes the value at path. It fails with ErrPathNotFound when the
+// path does not exist.
+func (d *YAMLDocument) Delete(path string) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		d.root.Content = nil
+		return nil
+	}
+	notFound := fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	node := d.content()
+	if node == nil {
+		return notFound
+	}
+	for _, s := range segments[:len(segments)-1] {
+		node = directChild(node, s)
+		if node == nil {
+			return notFound
+		}
+		node = resolveAlias(node)
+	}
+
+	last := segments[len(segments)-1]
+	switch {
+	case last.isIndex && node.Kind == yaml.SequenceNode && last.index < len(node.Content):
+		node.Content = slices.Delete(node.Content, last.index, last.index+1)
+	case !last.isIndex && node.Kind == yaml.MappingNode:
+		i := mappingIndex(node, last.key)
+		if i < 0 {
+			return notFound
+		}
+		node.Content = slices.Delete(node.Content, i, i+2)
+	default:
+		return notFound
+	}
+	return nil
+}
+
+// Encode writes the document to w. Nested values are indented the way the
+// parsed document indented them unless Indent is provided.
+func (d *YAMLDocument) Encode(w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+	indent := d.indent
+	if o.hasIndent {
+		indent = o.indent
+	}
+	if len(d.root.Content) == 0 {
+		return nil
+	}
+	// yaml.v3 writes merge keys with an explicit "!!merge" tag unless
+	// their tag is left for the parser to resolve.
+	merges := mergeKeys(&d.root)
+	for _, key := range merges {
+		key.Tag = ""
+	}
+	defer func() {
+		for _, key := range merges {
+			key.Tag = "!!merge"
+		}
+	}()
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(&d.root); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Node returns the root node of the document for edits that the path
+// based methods do not cover.
+func (d *YAMLDocument) Node() *yaml.Node {
+	return &d.root
+}
+
+// mergeKeys collects the "<<" keys of every mapping in a node tree.
+func mergeKeys(node *yaml.Node) []*yaml.Node {
+	var keys []*yaml.Node
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag == "!!merge" {
+				keys = append(keys, node.Content[i])
+			}
+		}
+	}
+	for _, child := range node.Content {
+		keys = append(keys, mergeKeys(child)...)
+	}
+	return keys
+}
+
+// content returns the root content node, or nil for an empty document.
+func (d *YAMLDocument) content() *yaml.Node {
+	if len(d.root.Content) == 0 {
+		return nil
+	}
+	return resolveAlias(d.root.Content[0])
+}
+
+// childNode returns the child of a node addressed by a path segment,
+// following aliases and merge keys.
+func childNode(node *yaml.Node, s pathSegment) *yaml.Node {
+	if child := directChild(node, s); child != nil {
+		return resolveAlias(child)
+	}
+	if s.isIndex || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	// Keys merged with "<<" are visible but not stored in the mapping.
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "<<" || node.Content[i].ShortTag() != "!!merge" {
+			continue
+		}
+		merged := resolveAlias(node.Content[i+1])
+		sources := []*yaml.Node{merged}
+		if merged.Kind == yaml.SequenceNode {
+			sources = merged.Content
+		}
+		for _, source := range sources {
+			if child := childNode(resolveAlias(source), s); child != nil {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// directChild returns the child stored in a mapping or sequence node.
+func directChild(node *yaml.Node, s pathSegment) *yaml.Node {
+	switch {
+	case s.isIndex && node.Kind == yaml.SequenceNode:
+		if s.index < len(node.Content) {
+			return node.Content[s.index]
+		}
+	case !s.isIndex && node.Kind == yaml.MappingNode:
+		if i := mappingIndex(node, s.key); i >= 0 {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingIndex returns the position of a key in a mapping node, or -1.
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i].ShortTag() != "!!merge" {
+			return i
+		}
+	}
+	return -1
+}
+
+// addChild adds a missing child to a mapping or appends it to a sequence.
+func addChild(node *yaml.Node, s pathSegment, child *yaml.Node) error {
+	switch {
+	case s.isIndex && node.Kind == yaml.SequenceNode:
+		if s.index != len(node.Content) {
+			return fmt.Errorf("index %d out of range for sequence of length %d", s.index, len(node.Content))
+		}
+		node.Content = append(node.Content, child)
+	case !s.isIndex && node.Kind == yaml.MappingNode:
+		node.Content = append(node.Content, stringNode(s.key), child)
+	case s.isIndex:
+		return errors.New("not a sequence")
+	default:
+		return errors.New("not a mapping")
+	}
+	return nil
+}
+
+// emptyContainer creates the node that a path segment indexes into.
+func emptyContainer(s pathSegment) *yaml.Node {
+	if s.isIndex {
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+// replaceNode overwrites a node in place, keeping its comments and anchor
+// so that aliases keep pointing at it.
+func replaceNode(old *yaml.Node, replacement *yaml.Node) {
+	replacement.HeadComment = old.HeadComment
+	replacement.LineComment = old.LineComment
+	replacement.FootComment = old.FootComment
+	replacement.Anchor = old.Anchor
+	if old.Kind == yaml.ScalarNode && replacement.Kind == yaml.ScalarNode &&
+		old.ShortTag() == "!!str" && replacement.ShortTag() == "!!str" && replacement.Style == 0 {
+		replacement.Style = old.Style
+	}
+	*old = *replacement
+}
+
+// resolveAlias follows an alias node to the node it refers to.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// detectIndent guesses the indentation of a YAML document from its first
+// indented line, falling back to 2 spaces.
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || len(trimmed) == len(line) {
+			continue
+		}
+		if indent := len(line) - len(trimmed); indent >= 2 && indent <= 9 {
+			return indent
+		}
+		break
+	}
+	return 2
+}
@@ -0,0 +1,174 @@
+package load
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEditableYAML = `# Service configuration
+name: api # the service name
+defaults: &defaults
+  timeout: 5s
+  retries: 3
+servers:
+  # primary first
+  - host: a.example
+    port: 80
+  - host: b.example
+    port: 81
+labels:
+  app.kubernetes.io/name: "api"
+backend:
+  <<: *defaults
+  url: http://backend
+`
+
+func readTestDocument(t *testing.T, input string) *YAMLDocument {
+	t.Helper()
+	doc, err := ReadYAMLDocument(strings.NewReader(input))
+	require.NoError(t, err)
+	return doc
+}
+
+func encodeTestDocument(t *testing.T, doc *YAMLDocument) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, doc.Encode(&buf))
+	return buf.String()
+}
+
+func TestYAMLDocument_RoundTrip(t *testing.T) {
+	doc := readTestDocument(t, testEditableYAML)
+	assert.Equal(t, testEditableYAML, encodeTestDocument(t, doc))
+}
+
+func TestYAMLDocument_Get(t *testing.T) {
+	doc := readTestDocument(t, testEditableYAML)
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{path: "name", want: "api"},
+		{path: "servers[1].port", want: 81},
+		{path: "$.servers[0].host", want: "a.example"},
+		{path: `labels["app.kubernetes.io/name"]`, want: "api"},
+		{path: "backend.retries", want: 3},
+		{path: "backend.url", want: "http://backend"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			switch want := tt.want.(type) {
+			case int:
+				var got int
+				require.NoError(t, doc.Get(tt.path, &got))
+				assert.Equal(t, want, got)
+			case string:
+				var got string
+				require.NoError(t, doc.Get(tt.path, &got))
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+
+	var server testEncodedServer
+	require.NoError(t, doc.Get("servers[0]", &server))
+	assert.Equal(t, testEncodedServer{Host: "a.example", Port: 80}, server)
+
+	var missing string
+	err := doc.Get("servers[5].host", &missing)
+	assert.ErrorIs(t, err, ErrPathNotFound)
+	assert.ErrorContains(t, err, "servers[5]")
+	assert.False(t, doc.Has("owner"))
+	assert.True(t, doc.Has("defaults.timeout"))
+}
+
+func TestYAMLDocument_Set(t *testing.T) {
+	doc := readTestDocument(t, testEditableYAML)
+
+	require.NoError(t, doc.Set("name", "gateway"))
+	require.NoError(t, doc.Set("servers[0].port", 8080))
+	require.NoError(t, doc.Set("servers[2]", testEncodedServer{Host: "c.example", Port: 82}))
+	require.NoError(t, doc.Set(`labels["app.kubernetes.io/name"]`, "gateway"))
+	require.NoError(t, doc.Set("owner.contact.email", "ops@example.com"))
+	require.NoError(t, doc.Set("backend.retries", 5))
+
+	assert.Equal(t, `# Service configuration
+name: gateway # the service name
+defaults: &defaults
+  timeout: 5s
+  retries: 3
+servers:
+  # primary first
+  - host: a.example
+    port: 8080
+  - host: b.example
+    port: 81
+  - host: c.example
+    port: 82
+labels:
+  app.kubernetes.io/name: "gateway"
+backend:
+  <<: *defaults
+  url: http://backend
+  retries: 5
+owner:
+  contact:
+    email: ops@example.com
+`, encodeTestDocument(t, doc))
+}
+
+func TestYAMLDocument_SetErrors(t *testing.T) {
+	doc := readTestDocument(t, testEditableYAML)
+
+	assert.ErrorContains(t, doc.Set("servers[7]", "x"), "index 7 out of range")
+	assert.ErrorContains(t, doc.Set("name.first", "x"), "not a mapping")
+	assert.ErrorContains(t, doc.Set("servers[", "x"), "invalid path")
+}
+
+func TestYAMLDocument_Delete(t *testing.T) {
+	doc := readTestDocument(t, testEditableYAML)
+
+	require.NoError(t, doc.Delete("servers[1]"))
+	require.NoError(t, doc.Delete("labels"))
+	require.NoError(t, doc.Delete("backend.url"))
+	assert.ErrorIs(t, doc.Delete("backend.timeout"), ErrPathNotFound)
+	assert.ErrorIs(t, doc.Delete("servers[3]"), ErrPathNotFound)
+
+	assert.Equal(t, `# Service configuration
+name: api # the service name
+defaults: &defaults
+  timeout: 5s
+  retries: 3
+servers:
+  # primary first
+  - host: a.example
+    port: 80
+backend:
+  <<: *defaults
+`, encodeTestDocument(t, doc))
+}
+
+func TestYAMLDocument_Empty(t *testing.T) {
+	doc := readTestDocument(t, "")
+	assert.Equal(t, "", encodeTestDocument(t, doc))
+	assert.ErrorIs(t, doc.Get("a", new(string)), ErrPathNotFound)
+
+	require.NoError(t, doc.Set("servers[0].host", "a"))
+	assert.Equal(t, "servers:\n  - host: a\n", encodeTestDocument(t, doc))
+}
+
+func TestYAMLDocument_Indent(t *testing.T) {
+	input := "server:\n    host: a\n    port: 80\n"
+	doc := readTestDocument(t, input)
+	require.NoError(t, doc.Set("server.port", 81))
+	assert.Equal(t, "server:\n    host: a\n    port: 81\n", encodeTestDocument(t, doc))
+
+	var buf bytes.Buffer
+	require.NoError(t, doc.Encode(&buf, Indent(2)))
+	assert.Equal(t, "server:\n  host: a\n  port: 81\n", buf.String())
+}